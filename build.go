@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fogatlas/fogatlasctl/pkg/deployment"
+	"github.com/urfave/cli"
+)
+
+func handleDeploymentBuild(c *cli.Context) error {
+	if len(c.StringSlice("from-manifests")) == 0 {
+		return fmt.Errorf("Error: option --from-manifests is required")
+	}
+	depl, err := deployment.Build(c.StringSlice("from-manifests"), deployment.BuildOptions{
+		Name:               c.String("name"),
+		Description:        c.String("description"),
+		ExternalEndpointID: c.String("externalendpoint_id"),
+		BandwidthRequired:  c.Int64("bandwidth_required"),
+		LatencyRequired:    c.Int64("latency_required"),
+	})
+	if err != nil {
+		return fmt.Errorf("Error: %s", err)
+	}
+	out, err := json.MarshalIndent(depl, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error: unable to encode deployment: %s", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/fogatlas/client-go/client/operations"
+	"github.com/fogatlas/fogatlasctl/pkg/api"
+	"github.com/fogatlas/fogatlasctl/pkg/audit"
+	"github.com/urfave/cli"
+)
+
+// auditRun identifies this invocation of fogatlasctl in the audit log.
+var auditRun = audit.NewRun()
+
+// newClient returns a client of the endpoint of the command, recording the
+// requests that change resources in the audit log.
+func newClient(c *cli.Context) *operations.Client {
+	transport := &audit.Transport{
+		Path:    audit.Path(),
+		User:    audit.CurrentUser(),
+		Context: c.String("endpoint"),
+		Command: c.Command.Name,
+		Run:     auditRun,
+		Errors:  os.Stderr,
+	}
+	return api.NewClientWithHTTP(c.String("endpoint"), &http.Client{Transport: transport})
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fogatlas/fogatlasctl/pkg/api"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/urfave/cli"
+)
+
+// completionCacheTTL is how long the identifiers retrieved for the completion
+// are kept on disk.
+const completionCacheTTL = 30 * time.Second
+
+// completionTimeout bounds the API requests made while completing, so that an
+// unreachable endpoint does not block the shell.
+const completionTimeout = 2 * time.Second
+
+// completionIDFlags maps the flags taking an identifier to the type of the
+// resource identified. The --id flag takes the type given as argument.
+var completionIDFlags = map[string]string{
+	"region_id":           "regions",
+	"node_id":             "nodes",
+	"externalendpoint_id": "externalendpoints",
+}
+
+// completionValues are the fixed values of some flags.
+var completionValues = map[string][]string{
+	"output":  {"table", "yaml", "json"},
+	"to":      {"kind", "grouped"},
+	"cascade": {"foreground", "orphan", "none"},
+}
+
+// completeCommand prints the candidates for the word being completed: the
+// flags of the command, the resource types it accepts or the values of a flag,
+// including the identifiers of the resources retrieved from the API. It is
+// called by the scripts printed by the completion command, which pass the
+// command line followed by --generate-bash-completion.
+func completeCommand(c *cli.Context) {
+	words := completionWords(c.Command.Name)
+	if len(words) == 0 {
+		// the command name itself is being completed
+		fmt.Println(c.Command.Name)
+		return
+	}
+	cur := words[len(words)-1]
+	words = words[:len(words)-1]
+
+	// flags maps every name of a flag to its first name
+	flags := map[string]string{}
+	valueFlags := map[string]bool{}
+	for _, flag := range c.Command.Flags {
+		names := strings.Split(flag.GetName(), ",")
+		for _, name := range names {
+			flags[strings.TrimSpace(name)] = names[0]
+		}
+		if _, isBool := flag.(cli.BoolFlag); !isBool {
+			valueFlags[names[0]] = true
+		}
+	}
+
+	args := []string{}
+	endpoint := api.DefaultEndpoint
+	pending := ""
+	for _, word := range words {
+		if pending != "" {
+			if pending == "endpoint" {
+				endpoint = word
+			}
+			pending = ""
+			continue
+		}
+		if !strings.HasPrefix(word, "-") || word == "-" {
+			args = append(args, word)
+			continue
+		}
+		name := strings.TrimLeft(word, "-")
+		if i := strings.Index(name, "="); i >= 0 {
+			if flags[name[:i]] == "endpoint" {
+				endpoint = name[i+1:]
+			}
+			continue
+		}
+		if valueFlags[flags[name]] {
+			pending = flags[name]
+		}
+	}
+
+	prefix := ""
+	if pending == "" && strings.HasPrefix(cur, "-") {
+		i := strings.Index(cur, "=")
+		if i < 0 {
+			for _, flag := range c.Command.Flags {
+				for _, name := range strings.Split(flag.GetName(), ",") {
+					name = strings.TrimSpace(name)
+					if len(name) == 1 {
+						fmt.Println("-" + name)
+					} else {
+						fmt.Println("--" + name)
+					}
+				}
+			}
+			return
+		}
+		pending = flags[strings.TrimLeft(cur[:i], "-")]
+		prefix = cur[:i+1]
+	}
+
+	res := ""
+	if len(args) > 0 {
+		if rt, err := resource.Lookup(args[0]); err == nil {
+			res = rt.Name
+		}
+	}
+	var candidates []string
+	switch {
+	case pending == "id":
+		if res != "" {
+			candidates = completionIDs(endpoint, res)
+		}
+	case completionIDFlags[pending] != "":
+		candidates = completionIDs(endpoint, completionIDFlags[pending])
+	case pending != "":
+		// other values, e.g. file names, are left to the shell
+		candidates = completionValues[pending]
+	case len(args) == 0:
+		candidates = argsUsageResources(c.Command.ArgsUsage)
+	case res != "" && strings.Contains(c.Command.ArgsUsage, "..."):
+		// identifiers given as arguments
+		candidates = completionIDs(endpoint, res)
+	}
+	for _, candidate := range candidates {
+		fmt.Println(prefix + candidate)
+	}
+}
+
+// completionWords returns the words of the command line following the command
+// name, without the completion flag.
+func completionWords(command string) []string {
+	words := os.Args[1:]
+	if n := len(words); n > 0 && words[n-1] == "--"+cli.BashCompletionFlag.GetName() {
+		words = words[:n-1]
+	}
+	for i, word := range words {
+		if word == command {
+			return words[i+1:]
+		}
+	}
+	return nil
+}
+
+// argsUsageResources returns the resource types listed in the usage of a
+// command, e.g. {regions|nodes} [ID...].
+func argsUsageResources(usage string) []string {
+	fields := strings.Fields(usage)
+	if len(fields) == 0 {
+		return nil
+	}
+	first := strings.Trim(fields[0], "{}[]")
+	if first == "" {
+		return nil
+	}
+	return strings.Split(first, "|")
+}
+
+// completionIDs returns the identifiers of the resources of the given type,
+// reading them from the cache when it is recent enough.
+func completionIDs(endpoint string, res string) []string {
+	cacheFile := completionCacheFile(endpoint, res)
+	if info, err := os.Stat(cacheFile); err == nil && time.Since(info.ModTime()) < completionCacheTTL {
+		if data, err := ioutil.ReadFile(cacheFile); err == nil {
+			return strings.Fields(string(data))
+		}
+	}
+
+	rt, err := resource.Lookup(res)
+	if err != nil {
+		return nil
+	}
+	client := api.NewClientWithHTTP(endpoint, &http.Client{Timeout: completionTimeout})
+	objs, err := resource.List(context.Background(), client, rt.Name, nil)
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, obj := range objs {
+		ids = append(ids, rt.ID(obj))
+	}
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0700); err == nil {
+		ioutil.WriteFile(cacheFile, []byte(strings.Join(ids, "\n")+"\n"), 0600)
+	}
+	return ids
+}
+
+// completionCacheFile returns the file caching the identifiers of a resource type.
+func completionCacheFile(endpoint string, resource string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	name := regexp.MustCompile(`[^A-Za-z0-9.-]`).ReplaceAllString(endpoint, "_") + "-" + resource
+	return filepath.Join(dir, "fogatlasctl", "completion", name)
+}
+
+// completionScripts are the scripts loading the completion in each shell. They
+// run the command line typed so far, including the word being completed, with
+// --generate-bash-completion.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for fogatlasctl, load with: source <(fogatlasctl completion bash)
+_fogatlasctl_complete() {
+    local line words word cur opts
+    # split on spaces only, bash would also split host:port and --flag=value
+    line="${COMP_LINE:0:$COMP_POINT}"
+    read -r -a words <<< "$line"
+    if [[ "$line" == *" " ]]; then
+        words+=("")
+    fi
+    word="${words[${#words[@]}-1]}"
+    opts=$(fogatlasctl "${words[@]:1}" --generate-bash-completion 2>/dev/null)
+    # bash replaces only the part of the word after the last = or :
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    if [[ "$cur" == "=" || "$cur" == ":" ]]; then
+        cur=""
+    fi
+    COMPREPLY=($(compgen -W "${opts}" -- "${word}"))
+    COMPREPLY=("${COMPREPLY[@]#"${word%"$cur"}"}")
+    return 0
+}
+complete -o default -F _fogatlasctl_complete fogatlasctl factl
+`,
+	"zsh": `#compdef fogatlasctl factl
+# zsh completion for fogatlasctl, load with: source <(fogatlasctl completion zsh)
+_fogatlasctl() {
+    local -a opts
+    opts=("${(@f)$(fogatlasctl "${(@)words[2,CURRENT]}" --generate-bash-completion 2>/dev/null)}")
+    if [[ -z "${opts[1]}" ]]; then
+        _files
+    else
+        compadd -a opts
+    fi
+}
+compdef _fogatlasctl fogatlasctl factl
+`,
+	"fish": `# fish completion for fogatlasctl, load with: fogatlasctl completion fish | source
+function __fogatlasctl_complete
+    set -l args (commandline -opc)
+    set -e args[1]
+    set -l cur (commandline -ct)
+    set -l opts (fogatlasctl $args "$cur" --generate-bash-completion 2>/dev/null)
+    if test (count $opts) -eq 0
+        __fish_complete_path $cur
+    else
+        printf '%s\n' $opts
+    end
+end
+complete -c fogatlasctl -f -a '(__fogatlasctl_complete)'
+complete -c factl -f -a '(__fogatlasctl_complete)'
+`,
+}
+
+func handleCompletion(c *cli.Context) error {
+	shell := c.Args().Get(0)
+	script, ok := completionScripts[shell]
+	if !ok {
+		return fmt.Errorf("Error: shell specified (%s) is not supported, use bash, zsh or fish", shell)
+	}
+	fmt.Print(script)
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/fogatlas/client-go/client/operations"
+	"github.com/fogatlas/fogatlasctl/pkg/api"
+	"github.com/fogatlas/fogatlasctl/pkg/describe"
+	"github.com/fogatlas/fogatlasctl/pkg/printer"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/urfave/cli"
+)
+
+// describer describes the resources of a type: fetch gathers a resource and
+// the related ones, table prints the view returned by fetch.
+type describer struct {
+	fetch func(ctx context.Context, client *operations.Client, id string) (interface{}, error)
+	table func(view interface{})
+}
+
+// describers are the resource types that can be described, by type name.
+var describers = map[string]describer{
+	"regions": {
+		fetch: func(ctx context.Context, client *operations.Client, id string) (interface{}, error) {
+			return describe.Region(ctx, client, id)
+		},
+		table: func(view interface{}) {
+			v := view.(*describe.RegionView)
+			printField("Region", v.Region.ID)
+			printField("Description", v.Region.Description)
+			printField("Location", v.Region.Location)
+			printField("Tier", strconv.FormatInt(v.Region.Tier, 10))
+			printCapacity(v.Capacity)
+			printSection("Nodes", "nodes", v.Nodes)
+			printSection("Microservices", "microservices", v.Microservices)
+			printSection("Relationships", "relationships", v.Relationships)
+			printSection("External endpoints", "externalendpoints", v.ExternalEndpoints)
+			printSection("Dynamic nodes", "dynamicnodes", v.DynamicNodes)
+		},
+	},
+	"nodes": {
+		fetch: func(ctx context.Context, client *operations.Client, id string) (interface{}, error) {
+			return describe.Node(ctx, client, id)
+		},
+		table: func(view interface{}) {
+			v := view.(*describe.NodeView)
+			printField("Node", v.Node.ID)
+			printField("Region", v.Node.RegionID)
+			if v.Region != nil {
+				printField("Region tier", strconv.FormatInt(v.Region.Tier, 10))
+				printField("Region location", v.Region.Location)
+			}
+			printField("Architecture", v.Node.Architecture)
+			printField("Distribution", v.Node.Distribution+" "+v.Node.Version)
+			printField("Status", v.Node.Status)
+			printCapacity(v.Capacity)
+			printSection("Microservices", "microservices", v.Microservices)
+			printSection("Dynamic nodes", "dynamicnodes", v.DynamicNodes)
+		},
+	},
+	"deployments": {
+		fetch: func(ctx context.Context, client *operations.Client, id string) (interface{}, error) {
+			return describe.Deployment(ctx, client, id)
+		},
+		table: func(view interface{}) {
+			v := view.(*describe.DeploymentView)
+			printField("Deployment", v.Deployment.Name)
+			printField("Description", v.Deployment.Description)
+			printField("Status", v.Deployment.Status)
+			printField("External endpoint", v.Deployment.ExternalendpointID)
+			if v.ExternalEndpoint != nil {
+				printField("Endpoint region", v.ExternalEndpoint.RegionID)
+			}
+			r := v.Requirements
+			printField("Requirements", fmt.Sprintf("%d microservices", r.Microservices))
+			printField("  CPU", r.CPURequired)
+			printField("  Memory", r.MemoryRequired)
+			printField("  Disk", r.DiskRequired)
+			printField("  Price", fmt.Sprintf("%.2f required, %.2f computed", r.PriceRequired, r.PriceComputed))
+			printWarnings(r.Warnings)
+			// the tables of the microservices and dataflows, the deployment
+			// itself is described above
+			rt, _ := resource.Lookup("deployments")
+			fmt.Println()
+			printer.Tables(os.Stdout, &resource.Type{Tables: rt.Tables[1:]}, []interface{}{v.Deployment})
+			printSection("Regions", "regions", v.Regions)
+		},
+	},
+	"applications": {
+		fetch: func(ctx context.Context, client *operations.Client, id string) (interface{}, error) {
+			return describe.Application(ctx, client, id)
+		},
+		table: func(view interface{}) {
+			v := view.(*describe.ApplicationView)
+			printField("Application", v.Application.ID)
+			printField("Name", v.Application.Name)
+			printField("Description", v.Application.Description)
+			printField("Status", v.Application.Status)
+			printSection("Microservices", "microservices", v.Microservices)
+			printSection("Nodes", "nodes", v.Nodes)
+			printSection("Regions", "regions", v.Regions)
+		},
+	},
+}
+
+func handleDescribe(c *cli.Context) error {
+	ctx := context.Background()
+	client := api.NewClient(c.String("endpoint"))
+	rt, ids, err := resourceArgs(c)
+	if err != nil {
+		return err
+	}
+	d, ok := describers[rt.Name]
+	if !ok {
+		var names []string
+		for _, t := range resource.Types {
+			if _, ok := describers[t.Name]; ok {
+				names = append(names, t.Name)
+			}
+		}
+		return fmt.Errorf("Error: resource specificed (%s) cannot be described, use %s", rt.Name, strings.Join(names, ", "))
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("Error: an identifier (as argument or with --id) is required")
+	}
+	for i, id := range ids {
+		view, err := d.fetch(ctx, client, id)
+		if err != nil {
+			return fmt.Errorf("Error: describe %s failed: %s", rt.Name, err)
+		}
+
+		if i > 0 {
+			if c.String("output") == "yaml" {
+				fmt.Println("---")
+			} else if c.String("output") == "table" {
+				fmt.Println()
+			}
+		}
+		if c.String("output") != "table" {
+			if err := printObject(view, c.String("output")); err != nil {
+				return err
+			}
+			continue
+		}
+		d.table(view)
+	}
+	return nil
+}
+
+// printField prints a field of a description, skipping the empty ones.
+func printField(name string, value string) {
+	if value != "" {
+		fmt.Printf("%-20s%s\n", name+":", value)
+	}
+}
+
+// printCapacity prints the capacity of a set of nodes.
+func printCapacity(capacity describe.Capacity) {
+	printField("Capacity", fmt.Sprintf("%d nodes", capacity.Nodes))
+	printField("  CPU", fmt.Sprintf("%s (%s available)", capacity.CPUCapacity, capacity.CPUAvailable))
+	printField("  Memory", fmt.Sprintf("%s (%s available)", capacity.MemoryCapacity, capacity.MemoryAvailable))
+	printField("  Disk", fmt.Sprintf("%s (%s available)", capacity.DiskCapacity, capacity.DiskAvailable))
+	printWarnings(capacity.Warnings)
+}
+
+// printWarnings prints the quantities that could not be added.
+func printWarnings(warnings []string) {
+	for _, w := range warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
+}
+
+// printSection prints related resources as the tables of their type. objs is
+// a slice of models.
+func printSection(title string, res string, objs interface{}) {
+	rt, _ := resource.Lookup(res)
+	var list []interface{}
+	v := reflect.ValueOf(objs)
+	for i := 0; i < v.Len(); i++ {
+		list = append(list, v.Index(i).Interface())
+	}
+	fmt.Println()
+	if len(list) == 0 {
+		fmt.Printf("%s: none\n", title)
+		return
+	}
+	fmt.Printf("%s\n", title)
+	printer.Tables(os.Stdout, rt, list)
+}
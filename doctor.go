@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fogatlas/fogatlasctl/pkg/api"
+	"github.com/fogatlas/fogatlasctl/pkg/doctor"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli"
+)
+
+func handleDoctor(c *cli.Context) error {
+	client := api.NewClient(c.String("endpoint"))
+	findings, err := doctor.Check(context.Background(), client)
+	if err != nil {
+		return fmt.Errorf("Error: doctor failed: %s", err)
+	}
+
+	if c.String("output") != "table" {
+		if !c.Bool("fix") {
+			for i := range findings {
+				findings[i].Fix = ""
+			}
+		}
+		if err := printObject(map[string]interface{}{"findings": findings}, c.String("output")); err != nil {
+			return err
+		}
+	} else if len(findings) > 0 {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Resource", "ID", "Kind", "Problem"})
+		for _, f := range findings {
+			table.Append([]string{f.Resource, f.ID, f.Kind, f.Problem})
+		}
+		table.Render()
+		if c.Bool("fix") {
+			fmt.Printf("Suggested fixes\n")
+			for _, f := range findings {
+				fmt.Printf("  %s %s: %s\n", f.Resource, f.ID, f.Fix)
+			}
+		}
+	}
+
+	if len(findings) > 0 {
+		return fmt.Errorf("Error: doctor found %d problems", len(findings))
+	}
+	if c.String("output") == "table" {
+		fmt.Printf("no problems found\n")
+	}
+	return nil
+}
@@ -0,0 +1,733 @@
+package main
+
+// End-to-end tests: the commands run as from the shell against an in-process
+// FogAtlas API (pkg/mock) seeded with testdata/e2e/seed.yaml. The output of
+// the commands reading resources is compared with the golden files in
+// testdata/e2e, rewritten with
+//
+//	go test -run E2E -update
+//
+// The commands changing resources are checked through the requests they send
+// and the resources left on the API, since their output is the message of the
+// API.
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fogatlas/fogatlasctl/pkg/audit"
+	"github.com/fogatlas/fogatlasctl/pkg/manifest"
+	"github.com/fogatlas/fogatlasctl/pkg/mock"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata/e2e")
+
+// editorReplace is the variable telling the test binary to act as the editor
+// of the edit command, see editor.
+const editorReplace = "FOGATLASCTL_E2E_EDITOR_REPLACE"
+
+func TestMain(m *testing.M) {
+	if expr := os.Getenv(editorReplace); expr != "" {
+		os.Exit(editor(expr, os.Args[len(os.Args)-1]))
+	}
+	flag.Parse()
+	// keep the configuration, the audit log and the completion cache of the
+	// user out of the tests
+	dir, err := ioutil.TempDir("", "fogatlasctl-e2e")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("FOGATLASCTL_CONFIG", filepath.Join(dir, "config.yaml"))
+	os.Setenv("FOGATLASCTL_AUDIT_LOG", filepath.Join(dir, "audit.jsonl"))
+	os.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	os.Setenv("USER", "tester")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// editor replaces old with new in file, given expr as old/new, like
+// sed -i s/old/new/ on any platform. It returns the exit code.
+func editor(expr string, file string) int {
+	parts := strings.SplitN(expr, "/", 2)
+	data, err := ioutil.ReadFile(file)
+	if err == nil && len(parts) == 2 {
+		err = ioutil.WriteFile(file, []byte(strings.Replace(string(data), parts[0], parts[1], -1)), 0600)
+	}
+	if err != nil || len(parts) != 2 {
+		fmt.Fprintf(os.Stderr, "editor: %s %v\n", expr, err)
+		return 1
+	}
+	return 0
+}
+
+// fakeAPI is a mock API served over http that records the requests received.
+type fakeAPI struct {
+	*mock.Server
+	srv *httptest.Server
+	// served, if set, is called after each request has been served
+	served func(api *fakeAPI, request string)
+
+	mu       sync.Mutex
+	requests []string
+}
+
+// newFakeAPI returns a fake API seeded with testdata/e2e/seed.yaml. The
+// deployments keep their status unless opts sets TransitionDelay.
+func newFakeAPI(t *testing.T, opts mock.Options) *fakeAPI {
+	conf, err := manifest.Load(manifest.Options{Files: []string{"testdata/e2e/seed.yaml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.TransitionDelay == 0 {
+		opts.TransitionDelay = time.Hour
+	}
+	api := &fakeAPI{Server: mock.NewServer(opts)}
+	if err := api.Seed(conf); err != nil {
+		t.Fatal(err)
+	}
+	api.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := r.Method + " " + r.URL.RequestURI()
+		api.mu.Lock()
+		api.requests = append(api.requests, request)
+		api.mu.Unlock()
+		api.Server.ServeHTTP(w, r)
+		if api.served != nil {
+			api.served(api, request)
+		}
+	}))
+	t.Cleanup(api.srv.Close)
+	return api
+}
+
+// endpoint is the value of --endpoint for the fake API.
+func (api *fakeAPI) endpoint() string {
+	return strings.TrimPrefix(api.srv.URL, "http://")
+}
+
+// takeRequests returns the requests received since the last call, as
+// "METHOD /path?query".
+func (api *fakeAPI) takeRequests() []string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	requests := api.requests
+	api.requests = nil
+	return requests
+}
+
+// document returns the json document of a resource, nil if it does not
+// exist.
+func (api *fakeAPI) document(res string, id string) map[string]interface{} {
+	rt, err := resource.Lookup(res)
+	if err != nil {
+		return nil
+	}
+	for _, doc := range api.Resources(rt.Name) {
+		if doc[rt.IDKey()] == id {
+			return doc
+		}
+	}
+	return nil
+}
+
+// field returns a field of the json document of a resource, nil if the
+// resource or the field does not exist.
+func (api *fakeAPI) field(res string, id string, name string) interface{} {
+	return api.document(res, id)[name]
+}
+
+// change sets a field of a resource as someone else would, without recording
+// the request.
+func (api *fakeAPI) change(t *testing.T, res string, id string, name string, value interface{}) {
+	doc := api.document(res, id)
+	doc[name] = value
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	rec := httptest.NewRecorder()
+	api.Server.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v2.0.0/"+res+"/"+id, bytes.NewReader(data)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("change of %s %s failed: %d %s", res, id, rec.Code, rec.Body)
+	}
+}
+
+// run runs a command line, without the program name, and returns what it
+// printed on stdout. {endpoint} in the arguments is replaced by the endpoint
+// of api. The standard input is empty, so confirmations are refused.
+func run(t *testing.T, api *fakeAPI, args ...string) (string, error) {
+	t.Helper()
+	// a new invocation of fogatlasctl in the audit log, as from the shell
+	auditRun = audit.NewRun()
+	argv := []string{"fogatlasctl"}
+	for _, arg := range args {
+		if api != nil {
+			arg = strings.Replace(arg, "{endpoint}", api.endpoint(), -1)
+		}
+		argv = append(argv, arg)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	stdout, oldStdin := os.Stdout, os.Stdin
+	os.Stdout, os.Stdin = w, stdin
+	var out bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&out, r)
+		close(done)
+	}()
+	err = newApp().Run(argv)
+	os.Stdout, os.Stdin = stdout, oldStdin
+	w.Close()
+	<-done
+	r.Close()
+	return out.String(), err
+}
+
+// checkGolden compares output with testdata/e2e/<name>.golden.
+func checkGolden(t *testing.T, name string, output string) {
+	t.Helper()
+	file := filepath.Join("testdata", "e2e", name+".golden")
+	if *updateGolden {
+		if err := ioutil.WriteFile(file, []byte(output), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%s (run with -update to create it)", err)
+	}
+	if output != string(want) {
+		t.Errorf("output differs from %s:\n--- got\n%s--- want\n%s", file, output, want)
+	}
+}
+
+func TestE2EReadCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		requests []string
+	}{
+		{
+			name:     "get-regions",
+			args:     []string{"get", "--endpoint={endpoint}", "regions"},
+			requests: []string{"GET /api/v2.0.0/regions"},
+		},
+		{
+			name:     "get-nodes-region",
+			args:     []string{"get", "--endpoint={endpoint}", "--region_id=EDGEA", "nodes"},
+			requests: []string{"GET /api/v2.0.0/nodes?region_id=EDGEA"},
+		},
+		{
+			name:     "get-nodes-ids-yaml",
+			args:     []string{"get", "--endpoint={endpoint}", "-o", "yaml", "node", "edge1", "cloud1"},
+			requests: []string{"GET /api/v2.0.0/nodes/edge1", "GET /api/v2.0.0/nodes/cloud1"},
+		},
+		{
+			name:     "get-deployments-json",
+			args:     []string{"get", "--endpoint={endpoint}", "-o", "json", "--status=deployed", "deployments"},
+			requests: []string{"GET /api/v2.0.0/deployments?status=deployed"},
+		},
+		{
+			name: "get-nodes-selector",
+			args: []string{"get", "--endpoint={endpoint}", "--field-selector", "status=up", "-l", "region.tier in (1)", "--sort-by", "cpu_available", "nodes"},
+			requests: []string{
+				"GET /api/v2.0.0/nodes",
+				"GET /api/v2.0.0/regions",
+			},
+		},
+		{
+			name: "describe-region",
+			args: []string{"describe", "--endpoint={endpoint}", "region", "EDGEA"},
+		},
+		{
+			name: "describe-deployment",
+			args: []string{"describe", "--endpoint={endpoint}", "deployment", "traffic"},
+		},
+		{
+			name: "doctor",
+			args: []string{"doctor", "--endpoint={endpoint}", "--fix"},
+		},
+		{
+			name: "convert",
+			args: []string{"convert", "-f", "testdata/e2e/seed.yaml"},
+		},
+		{
+			name: "render",
+			args: []string{"render", "-f", "testdata/e2e/template.yaml.tmpl", "--set", "prefix=test-"},
+		},
+		{
+			name: "validate",
+			args: []string{"validate", "-f", "testdata/e2e/putall.yaml"},
+		},
+		{
+			name: "deployment-build",
+			args: []string{"deployment", "build", "--from-manifests", "testdata/e2e/k8s.yaml", "--name", "detection"},
+		},
+		{
+			name: "completion-bash",
+			args: []string{"completion", "bash"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI(t, mock.Options{})
+			out, err := run(t, api, tt.args...)
+			if err != nil {
+				t.Fatalf("%s failed: %s\n%s", tt.args[0], err, out)
+			}
+			checkGolden(t, tt.name, out)
+			requests := api.takeRequests()
+			if tt.requests != nil && !reflect.DeepEqual(requests, tt.requests) {
+				t.Errorf("requests:\n  %s\nwant:\n  %s", strings.Join(requests, "\n  "), strings.Join(tt.requests, "\n  "))
+			}
+			for _, req := range requests {
+				if !strings.HasPrefix(req, "GET ") {
+					t.Errorf("%s changed the API: %s", tt.args[0], req)
+				}
+			}
+		})
+	}
+}
+
+// editedDescriptor is a deployment descriptor written in flow style, which
+// edit shows as a yaml object.
+const editedDescriptor = `kind: Deployment
+apiVersion: apps/v1
+metadata: {name: detector}
+spec:
+  selector: {matchLabels: {app: detector}}
+  template: {spec: {containers: [{name: detector, image: "example/detector:1.0", resources: {requests: {cpu: 500m, memory: 512Mi}}}]}}
+`
+
+func TestE2EChangeCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		opts mock.Options
+		// setup runs before the command; the protections it adds are
+		// removed at the end of the test
+		setup  [][]string
+		served func(t *testing.T, api *fakeAPI, request string)
+		args   []string
+		env    map[string]string
+		// err is the beginning of the error expected
+		err      string
+		requests []string
+		check    func(t *testing.T, api *fakeAPI)
+	}{
+		{
+			name: "put",
+			args: []string{"put", "--endpoint={endpoint}", "-f", "testdata/e2e/region.yaml", "regions"},
+			requests: []string{
+				"GET /api/v2.0.0/regions/EDGEB",
+				"PUT /api/v2.0.0/regions/EDGEB",
+			},
+			check: func(t *testing.T, api *fakeAPI) {
+				if got := api.field("regions", "EDGEB", "location"); got != "Rovereto" {
+					t.Errorf("location of EDGEB is %v", got)
+				}
+			},
+		},
+		{
+			name: "patch-status",
+			args: []string{"patch", "--endpoint={endpoint}", "--status=toundeploy", "deployments", "traffic"},
+			requests: []string{
+				"GET /api/v2.0.0/deployments/traffic",
+				"PATCH /api/v2.0.0/deployments/traffic",
+			},
+			check: func(t *testing.T, api *fakeAPI) {
+				if got := api.field("deployments", "traffic", "status"); got != "toundeploy" {
+					t.Errorf("status of traffic is %v", got)
+				}
+			},
+		},
+		{
+			name: "patch-merge",
+			args: []string{"patch", "--endpoint={endpoint}", "--patch", `{"tier": 2, "description": null}`, "regions", "CLOUD"},
+			requests: []string{
+				"GET /api/v2.0.0/regions/CLOUD",
+				"GET /api/v2.0.0/regions/CLOUD",
+				"GET /api/v2.0.0/regions/CLOUD",
+				"PUT /api/v2.0.0/regions/CLOUD",
+			},
+			check: func(t *testing.T, api *fakeAPI) {
+				if got := api.field("regions", "CLOUD", "tier"); got != 2.0 {
+					t.Errorf("tier of CLOUD is %v", got)
+				}
+				if got := api.field("regions", "CLOUD", "description"); got != nil {
+					t.Errorf("description of CLOUD is %v", got)
+				}
+			},
+		},
+		{
+			name: "set",
+			args: []string{"set", "--endpoint={endpoint}", "nodes", "edge1", "edge2", "status=maintenance"},
+			requests: []string{
+				"GET /api/v2.0.0/nodes/edge1",
+				"GET /api/v2.0.0/nodes/edge1",
+				"GET /api/v2.0.0/nodes/edge1",
+				"PUT /api/v2.0.0/nodes/edge1",
+				"GET /api/v2.0.0/nodes/edge2",
+				"GET /api/v2.0.0/nodes/edge2",
+				"GET /api/v2.0.0/nodes/edge2",
+				"PUT /api/v2.0.0/nodes/edge2",
+			},
+			check: func(t *testing.T, api *fakeAPI) {
+				for _, id := range []string{"edge1", "edge2"} {
+					if got := api.field("nodes", id, "status"); got != "maintenance" {
+						t.Errorf("status of %s is %v", id, got)
+					}
+				}
+			},
+		},
+		{
+			name: "set-unchanged",
+			args: []string{"set", "--endpoint={endpoint}", "nodes", "edge1", "status=up"},
+			requests: []string{
+				"GET /api/v2.0.0/nodes/edge1",
+			},
+		},
+		{
+			name: "edit",
+			args: []string{"edit", "--endpoint={endpoint}", "regions", "EDGEA"},
+			env:  map[string]string{"EDITOR": os.Args[0], editorReplace: "Povo/Mattarello"},
+			requests: []string{
+				"GET /api/v2.0.0/regions/EDGEA",
+				"GET /api/v2.0.0/regions/EDGEA",
+				"GET /api/v2.0.0/regions/EDGEA",
+				"PUT /api/v2.0.0/regions/EDGEA",
+			},
+			check: func(t *testing.T, api *fakeAPI) {
+				if got := api.field("regions", "EDGEA", "location"); got != "Mattarello" {
+					t.Errorf("location of EDGEA is %v", got)
+				}
+			},
+		},
+		{
+			name:  "edit-deployment",
+			setup: [][]string{{"set", "--endpoint={endpoint}", "deployments", "traffic", "microservices.0.deployment_descriptor=" + editedDescriptor, "microservices.1.deployment_descriptor=" + editedDescriptor}},
+			args:  []string{"edit", "--endpoint={endpoint}", "deployments", "traffic"},
+			env:   map[string]string{"EDITOR": os.Args[0], editorReplace: "Traffic monitoring/Traffic watch"},
+			check: func(t *testing.T, api *fakeAPI) {
+				if got := api.field("deployments", "traffic", "description"); got != "Traffic watch" {
+					t.Errorf("description of traffic is %v", got)
+				}
+				for _, ms := range api.field("deployments", "traffic", "microservices").([]interface{}) {
+					ms := ms.(map[string]interface{})
+					if got := ms["deployment_descriptor"]; got != editedDescriptor {
+						t.Errorf("the descriptor of %v was rewritten:\n%v", ms["name"], got)
+					}
+				}
+			},
+		},
+		{
+			name: "delete",
+			args: []string{"delete", "--endpoint={endpoint}", "externalendpoints", "cam1"},
+			requests: []string{
+				"GET /api/v2.0.0/externalendpoints/cam1",
+				"DELETE /api/v2.0.0/externalendpoints/cam1",
+			},
+			check: func(t *testing.T, api *fakeAPI) {
+				if n := len(api.Resources("externalendpoints")); n != 0 {
+					t.Errorf("%d external endpoints left", n)
+				}
+			},
+		},
+		{
+			name: "delete-not-found",
+			args: []string{"delete", "--endpoint={endpoint}", "nodes", "nosuch"},
+			err:  "Error: 1 of 1 deletions failed",
+			requests: []string{
+				"GET /api/v2.0.0/nodes/nosuch",
+				"DELETE /api/v2.0.0/nodes/nosuch",
+			},
+		},
+		{
+			name: "delete-cascade-refused",
+			args: []string{"delete", "--endpoint={endpoint}", "--cascade=foreground", "regions", "EDGEA"},
+			err:  "Error: delete cancelled",
+			check: func(t *testing.T, api *fakeAPI) {
+				for _, req := range api.takeRequests() {
+					if !strings.HasPrefix(req, "GET ") {
+						t.Errorf("the API was changed without confirmation: %s", req)
+					}
+				}
+			},
+		},
+		{
+			name: "delete-cascade",
+			args: []string{"delete", "--endpoint={endpoint}", "--cascade=foreground", "--yes", "regions", "EDGEA"},
+			check: func(t *testing.T, api *fakeAPI) {
+				if n := len(api.Resources("regions")); n != 1 {
+					t.Errorf("%d regions left", n)
+				}
+				for _, doc := range api.Resources("nodes") {
+					if doc["region_id"] == "EDGEA" {
+						t.Errorf("node %v of EDGEA left", doc["id"])
+					}
+				}
+			},
+		},
+		{
+			name:  "delete-protected",
+			setup: [][]string{{"protect", "nodes", "edge*"}},
+			args:  []string{"delete", "--endpoint={endpoint}", "nodes", "edge2"},
+			err:   "Error: protected resources cannot be deleted without --force-protected: nodes/edge2 (* nodes/edge*)",
+			check: func(t *testing.T, api *fakeAPI) {
+				if requests := api.takeRequests(); len(requests) != 0 {
+					t.Errorf("requests sent: %v", requests)
+				}
+			},
+		},
+		{
+			name: "putAll",
+			args: []string{"putAll", "--endpoint={endpoint}", "-f", "testdata/e2e/putall.yaml"},
+			requests: []string{
+				"GET /api/v2.0.0/regions/EDGEB",
+				"GET /api/v2.0.0/nodes/edge3",
+				"GET /api/v2.0.0/regions/EDGEB",
+				"GET /api/v2.0.0/regions/EDGEB",
+				"PUT /api/v2.0.0/regions/EDGEB",
+				"GET /api/v2.0.0/nodes/edge3",
+				"GET /api/v2.0.0/nodes/edge3",
+				"PUT /api/v2.0.0/nodes/edge3",
+			},
+			check: func(t *testing.T, api *fakeAPI) {
+				if got := api.field("nodes", "edge3", "region_id"); got != "EDGEB" {
+					t.Errorf("region of edge3 is %v", got)
+				}
+			},
+		},
+		{
+			name: "putAll-atomic-rollback",
+			opts: mock.Options{
+				ErrorRate: 1,
+				ErrorMatch: func(r *http.Request) bool {
+					return r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/api/v2.0.0/nodes/")
+				},
+			},
+			args: []string{"putAll", "--endpoint={endpoint}", "--atomic", "-f", "testdata/e2e/putall.yaml"},
+			err:  "Error: putAll aborted: put nodes edge3 failed",
+			requests: []string{
+				"GET /api/v2.0.0/regions/EDGEB",
+				"GET /api/v2.0.0/nodes/edge3",
+				"GET /api/v2.0.0/regions/EDGEB",
+				"GET /api/v2.0.0/regions/EDGEB",
+				"PUT /api/v2.0.0/regions/EDGEB",
+				"GET /api/v2.0.0/nodes/edge3",
+				"GET /api/v2.0.0/nodes/edge3",
+				"PUT /api/v2.0.0/nodes/edge3",
+				"GET /api/v2.0.0/regions/EDGEB",
+				"DELETE /api/v2.0.0/regions/EDGEB",
+			},
+			check: func(t *testing.T, api *fakeAPI) {
+				if doc := api.document("regions", "EDGEB"); doc != nil {
+					t.Errorf("EDGEB not rolled back: %v", doc)
+				}
+				if doc := api.document("nodes", "edge3"); doc != nil {
+					t.Errorf("edge3 created: %v", doc)
+				}
+			},
+		},
+		{
+			name: "putAll-conflict",
+			// someone else changes edge1 once putAll has retrieved it
+			served: func(t *testing.T, api *fakeAPI, request string) {
+				if request == "GET /api/v2.0.0/nodes/edge1" && api.field("nodes", "edge1", "cpu_available") != "1234m" {
+					api.change(t, "nodes", "edge1", "cpu_available", "1234m")
+				}
+			},
+			args: []string{"putAll", "--endpoint={endpoint}", "--atomic", "-f", "testdata/e2e/putall-conflict.yaml"},
+			err:  "Error: putAll aborted: nodes edge1 was changed by someone else in the meantime",
+			check: func(t *testing.T, api *fakeAPI) {
+				if got := api.field("nodes", "edge1", "status"); got != "up" {
+					t.Errorf("status of edge1 is %v", got)
+				}
+				if got := api.field("nodes", "edge1", "cpu_available"); got != "1234m" {
+					t.Errorf("the change of edge1 was overwritten: cpu_available is %v", got)
+				}
+				if doc := api.document("regions", "EDGEB"); doc != nil {
+					t.Errorf("EDGEB not rolled back: %v", doc)
+				}
+			},
+		},
+		{
+			name: "set-conflict",
+			// someone else changes edge1 once set has retrieved it
+			served: func(t *testing.T, api *fakeAPI, request string) {
+				if request == "GET /api/v2.0.0/nodes/edge1" && api.field("nodes", "edge1", "cpu_available") != "1234m" {
+					api.change(t, "nodes", "edge1", "cpu_available", "1234m")
+				}
+			},
+			args: []string{"set", "--endpoint={endpoint}", "nodes", "edge1", "status=maintenance"},
+			err:  "Error: 1 of 1 updates failed",
+			requests: []string{
+				"GET /api/v2.0.0/nodes/edge1",
+				"GET /api/v2.0.0/nodes/edge1",
+			},
+			check: func(t *testing.T, api *fakeAPI) {
+				if got := api.field("nodes", "edge1", "status"); got != "up" {
+					t.Errorf("status of edge1 is %v", got)
+				}
+				if got := api.field("nodes", "edge1", "cpu_available"); got != "1234m" {
+					t.Errorf("the change of edge1 was overwritten: cpu_available is %v", got)
+				}
+			},
+		},
+		{
+			name: "deleteAll",
+			args: []string{"deleteAll", "--endpoint={endpoint}", "microservices"},
+			requests: []string{
+				"GET /api/v2.0.0/microservices",
+				"GET /api/v2.0.0/microservices/dashboard",
+				"DELETE /api/v2.0.0/microservices/dashboard",
+				"GET /api/v2.0.0/microservices/detector",
+				"DELETE /api/v2.0.0/microservices/detector",
+			},
+			check: func(t *testing.T, api *fakeAPI) {
+				if n := len(api.Resources("microservices")); n != 0 {
+					t.Errorf("%d microservices left", n)
+				}
+			},
+		},
+		{
+			name:  "undo",
+			setup: [][]string{{"set", "--endpoint={endpoint}", "regions", "EDGEA", "tier=5"}},
+			args:  []string{"undo", "--endpoint={endpoint}", "--yes"},
+			requests: []string{
+				"GET /api/v2.0.0/regions/EDGEA",
+				"PUT /api/v2.0.0/regions/EDGEA",
+			},
+			check: func(t *testing.T, api *fakeAPI) {
+				if got := api.field("regions", "EDGEA", "tier"); got != 1.0 {
+					t.Errorf("tier of EDGEA is %v after undo", got)
+				}
+
+				out, err := run(t, api, "history", "--context={endpoint}", "-o", "json", "regions", "EDGEA")
+				if err != nil {
+					t.Fatalf("history failed: %s\n%s", err, out)
+				}
+				var history struct {
+					Entries []struct {
+						User, Command, Operation, Run, Undoes string
+						Status                                int
+					}
+				}
+				if err := json.Unmarshal([]byte(out), &history); err != nil {
+					t.Fatalf("wrong history %s: %s", out, err)
+				}
+				if n := len(history.Entries); n != 2 {
+					t.Fatalf("%d history entries, want 2:\n%s", n, out)
+				}
+				set, undo := history.Entries[0], history.Entries[1]
+				if set.User == "" || set.Command != "set" || set.Operation != "PUT" || set.Status != http.StatusOK {
+					t.Errorf("wrong entry of set: %+v", set)
+				}
+				if undo.Command != "undo" || undo.Operation != "PUT" || undo.Undoes != set.Run {
+					t.Errorf("wrong entry of undo: %+v", undo)
+				}
+
+				if _, err := run(t, api, "undo", "--endpoint={endpoint}", "--yes"); err == nil || !strings.HasPrefix(err.Error(), "Error: nothing to undo") {
+					t.Errorf("second undo returned %v", err)
+				}
+			},
+		},
+		{
+			name: "undo-protected",
+			setup: [][]string{
+				{"set", "--endpoint={endpoint}", "regions", "EDGEA", "tier=5"},
+				{"protect", "regions", "EDGE*"},
+			},
+			args: []string{"undo", "--endpoint={endpoint}", "--yes"},
+			err:  "Error: unable to undo: restore regions EDGEA (changed by set on ",
+			check: func(t *testing.T, api *fakeAPI) {
+				if requests := api.takeRequests(); len(requests) != 0 {
+					t.Errorf("requests sent: %v", requests)
+				}
+				if got := api.field("regions", "EDGEA", "tier"); got != 5.0 {
+					t.Errorf("tier of EDGEA is %v after a refused undo", got)
+				}
+				if out, err := run(t, api, "undo", "--endpoint={endpoint}", "--yes", "--force-protected"); err != nil {
+					t.Errorf("undo --force-protected failed: %s\n%s", err, out)
+				}
+				if got := api.field("regions", "EDGEA", "tier"); got != 1.0 {
+					t.Errorf("tier of EDGEA is %v after undo --force-protected", got)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI(t, tt.opts)
+			for key, value := range tt.env {
+				defer os.Setenv(key, os.Getenv(key))
+				os.Setenv(key, value)
+			}
+			for _, args := range tt.setup {
+				if out, err := run(t, api, args...); err != nil {
+					t.Fatalf("%s failed: %s\n%s", args[0], err, out)
+				}
+				if args[0] == "protect" {
+					unprotect := append([]string{"unprotect"}, args[1:]...)
+					t.Cleanup(func() {
+						if out, err := run(t, nil, unprotect...); err != nil {
+							t.Errorf("unprotect failed: %s\n%s", err, out)
+						}
+					})
+				}
+			}
+			api.takeRequests()
+			if tt.served != nil {
+				api.served = func(api *fakeAPI, request string) { tt.served(t, api, request) }
+			}
+
+			out, err := run(t, api, tt.args...)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("%s failed: %s\n%s", tt.args[0], err, out)
+			case tt.err != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.err)):
+				t.Fatalf("%s returned %v, want %s\n%s", tt.args[0], err, tt.err, out)
+			}
+			if tt.requests != nil {
+				if requests := api.takeRequests(); !reflect.DeepEqual(requests, tt.requests) {
+					t.Errorf("requests:\n  %s\nwant:\n  %s", strings.Join(requests, "\n  "), strings.Join(tt.requests, "\n  "))
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, api)
+			}
+		})
+	}
+}
+
+func TestE2EErrorStatus(t *testing.T) {
+	api := newFakeAPI(t, mock.Options{})
+	out, err := run(t, api, "get", "--endpoint={endpoint}", "regions", "nosuch")
+	if err == nil || !strings.Contains(err.Error(), "Error: get regions failed") || !strings.Contains(err.Error(), "404") {
+		t.Errorf("get of a missing region returned %v\n%s", err, out)
+	}
+}
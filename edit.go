@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/fogatlas/client-go/models"
+	"github.com/fogatlas/fogatlasctl/pkg/deployment"
+	"github.com/fogatlas/fogatlasctl/pkg/diff"
+	"github.com/fogatlas/fogatlasctl/pkg/manifest"
+	"github.com/fogatlas/fogatlasctl/pkg/printer"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/fogatlas/fogatlasctl/pkg/update"
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/strfmt"
+	"github.com/urfave/cli"
+)
+
+// editorCommand returns the command line of the editor: $EDITOR, vi if unset.
+func editorCommand() []string {
+	if editor := strings.Fields(os.Getenv("EDITOR")); len(editor) > 0 {
+		return editor
+	}
+	return []string{"vi"}
+}
+
+// runEditor opens file in the editor and waits for it to be closed.
+func runEditor(file string) error {
+	editor := editorCommand()
+	cmd := exec.Command(editor[0], append(editor[1:], file)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %s", editor[0], err)
+	}
+	return nil
+}
+
+// editHeader is the comment written at the top of the edited file, followed
+// by the problems found in the previous version, if any.
+func editHeader(res string, id string, problems []string) string {
+	header := fmt.Sprintf("# Edit %s %s. Lines starting with # are ignored and an empty file cancels the edit.\n", res, id)
+	if len(problems) > 0 {
+		header += "#\n# The resource is not valid:\n"
+		for _, p := range problems {
+			header += fmt.Sprintf("#   %s\n", p)
+		}
+	}
+	return header + "#\n"
+}
+
+// stripComments removes the lines starting with #.
+func stripComments(text string) string {
+	var lines []string
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// decodeEdited decodes an edited resource, returning the problems found: yaml
+// syntax, unknown fields, values rejected by the API model, a changed
+// identifier and, for deployments, errors in the descriptors. before is the
+// resource as retrieved, whose unchanged descriptors are kept as they were.
+func decodeEdited(rt *resource.Type, id string, before interface{}, text string) (interface{}, []string) {
+	data, err := yaml.YAMLToJSON([]byte(text))
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	data, err = manifest.ResolveDescriptors(data, ".")
+	if err != nil {
+		return nil, []string{fmt.Sprintf("wrong deployment descriptor: %s", err)}
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, []string{err.Error()}
+	}
+	obj, err := update.Decode(rt, doc)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	var problems []string
+	if v, ok := obj.(interface{ Validate(strfmt.Registry) error }); ok {
+		if err := v.Validate(strfmt.Default); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if objID := rt.ID(obj); objID != "" && objID != id {
+		problems = append(problems, fmt.Sprintf("the identifier cannot be changed (%s instead of %s), use put to create a copy", objID, id))
+	}
+	if depl, ok := obj.(*models.Deployment); ok {
+		if orig, ok := before.(*models.Deployment); ok {
+			keepDescriptors(orig, depl)
+		}
+		for _, p := range deployment.Validate(depl) {
+			if !p.Warning {
+				problems = append(problems, fmt.Sprintf("microservice %s: %s", p.Microservice, p.Message))
+			}
+		}
+	}
+	return obj, problems
+}
+
+// keepDescriptors gives back to the microservices of after the descriptors
+// of before that were not changed, since the editor shows them as yaml
+// objects and decoding re-marshals them.
+func keepDescriptors(before *models.Deployment, after *models.Deployment) {
+	descriptors := make(map[string]string)
+	for _, ms := range before.Microservices {
+		if ms != nil {
+			descriptors[ms.Name] = ms.DeploymentDescriptor
+		}
+	}
+	for _, ms := range after.Microservices {
+		if ms == nil {
+			continue
+		}
+		orig, ok := descriptors[ms.Name]
+		if !ok || orig == ms.DeploymentDescriptor {
+			continue
+		}
+		var x, y interface{}
+		if yaml.Unmarshal([]byte(orig), &x) != nil || yaml.Unmarshal([]byte(ms.DeploymentDescriptor), &y) != nil {
+			continue
+		}
+		if reflect.DeepEqual(x, y) {
+			ms.DeploymentDescriptor = orig
+		}
+	}
+}
+
+func handleEdit(c *cli.Context) error {
+	ctx := context.Background()
+	client := newClient(c)
+	rt, ids, err := resourceArgs(c)
+	if err != nil {
+		return err
+	}
+	if len(ids) != 1 {
+		return fmt.Errorf("Error: edit requires a single identifier")
+	}
+	id := ids[0]
+	obj, err := rt.Get(ctx, client, id)
+	if err != nil {
+		return fmt.Errorf("Error: get %s %s failed: %s", rt.Name, id, err)
+	}
+	var buf bytes.Buffer
+	if err := printer.Object(&buf, obj, "yaml"); err != nil {
+		return fmt.Errorf("Error: %s", err)
+	}
+	original := buf.String()
+
+	f, err := ioutil.TempFile("", "fogatlasctl-edit-*.yaml")
+	if err != nil {
+		return fmt.Errorf("Error: %s", err)
+	}
+	file := f.Name()
+	f.Close()
+	keep := false
+	defer func() {
+		if !keep {
+			os.Remove(file)
+		}
+	}()
+
+	text := original
+	var problems []string
+	var edited interface{}
+	for {
+		if err := ioutil.WriteFile(file, []byte(editHeader(rt.Name, id, problems)+text), 0600); err != nil {
+			return fmt.Errorf("Error: %s", err)
+		}
+		if err := runEditor(file); err != nil {
+			return fmt.Errorf("Error: %s", err)
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("Error: %s", err)
+		}
+		previous := text
+		text = stripComments(string(data))
+		if strings.TrimSpace(text) == "" {
+			fmt.Printf("Edit cancelled, no changes made\n")
+			return nil
+		}
+		if problems != nil && text == previous {
+			// the file was saved again without fixing the problems
+			return fmt.Errorf("Error: %s %s is not valid: %s", rt.Name, id, strings.Join(problems, "; "))
+		}
+		edited, problems = decodeEdited(rt, id, obj, text)
+		if len(problems) == 0 {
+			break
+		}
+	}
+
+	buf.Reset()
+	if err := printer.Object(&buf, edited, "yaml"); err != nil {
+		return fmt.Errorf("Error: %s", err)
+	}
+	changes := diff.Lines(original, buf.String())
+	if changes == "" {
+		fmt.Printf("Edit cancelled, no changes made\n")
+		return nil
+	}
+	fmt.Print(changes)
+	if err := update.Verify(ctx, client, rt.Name, id, obj); err != nil {
+		keep = true
+		return fmt.Errorf("Error: %s\nThe edited resource is in %s", err, file)
+	}
+	resp, err := rt.Put(ctx, client, id, edited)
+	if err != nil {
+		keep = true
+		return fmt.Errorf("Error: put %s failed (%s), the edited resource is in %s", rt.Name, err, file)
+	}
+	fmt.Printf("%s\n", resp)
+	return nil
+}
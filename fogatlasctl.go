@@ -1,33 +1,29 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"strconv"
+	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/ghodss/yaml"
-	httptransport "github.com/go-openapi/runtime/client"
-	"github.com/go-openapi/strfmt"
-	"github.com/olekukonko/tablewriter"
-	"github.com/urfave/cli"
-	apiclient "github.com/fogatlas/client-go/client"
 	"github.com/fogatlas/client-go/client/operations"
 	"github.com/fogatlas/client-go/models"
+	"github.com/fogatlas/fogatlasctl/pkg/api"
+	"github.com/fogatlas/fogatlasctl/pkg/apply"
+	"github.com/fogatlas/fogatlasctl/pkg/cascade"
+	"github.com/fogatlas/fogatlasctl/pkg/manifest"
+	"github.com/fogatlas/fogatlasctl/pkg/printer"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/fogatlas/fogatlasctl/pkg/selector"
+	"github.com/fogatlas/fogatlasctl/pkg/update"
+	"github.com/ghodss/yaml"
+	"github.com/urfave/cli"
 )
 
-type confFile struct {
-	Applications     []models.Application      `json:"applications,omitempty"`
-	Microservices    []models.Microservice     `json:"microservices,omitempty"`
-	Relationships    []models.Relationship     `json:"relationships,omitempty"`
-	Nodes            []models.Node             `json:"nodes,omitempty"`
-	Regions          []models.Region           `json:"regions,omitempty"`
-	ExternalEdpoints []models.ExternalEndpoint `json:"externalendpoints,tempty"`
-	DynamicNodes     []models.DynamicNode      `json:"dynamicnode,omitempty"`
-	Deployments      []models.Deployment       `json:"deployments,omitempty"`
-}
-
 func main() {
 	cli.AppHelpTemplate = `NAME:
      {{.Name}} - {{.Usage}}{{ "\n"}}
@@ -59,6 +55,14 @@ func main() {
      {{end}}{{end}}
   `
 
+	err := newApp().Run(os.Args)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+	}
+}
+
+// newApp returns the command line application with all the commands.
+func newApp() *cli.App {
 	app := cli.NewApp()
 	app.Name = "fogatlasctl"
 	app.Version = "1.3.0"
@@ -70,21 +74,22 @@ func main() {
 	}
 	app.Usage = "Command line interface for FogAtlas"
 	app.ArgsUsage = "resource"
+	app.EnableBashCompletion = true
 	app.Commands = []cli.Command{
 		cli.Command{
 			Name:      "get",
 			Usage:     "retrieve information on a resource",
-			ArgsUsage: "{applications|deployments|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes}",
+			ArgsUsage: "{applications|deployments|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes} [ID...]",
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "endpoint",
-					Value: "127.0.0.1:8080",
+					Value: api.DefaultEndpoint,
 					Usage: "API endpoint",
 				},
 				cli.StringFlag{
 					Name:  "id",
 					Value: "",
-					Usage: "identifier of the resource to be retrieved",
+					Usage: "identifier of the resource to be retrieved (identifiers can also be given as arguments)",
 				},
 				cli.StringFlag{
 					Name:  "region_id",
@@ -101,6 +106,31 @@ func main() {
 					Value: "",
 					Usage: "status of the deployment (valid only for deployments)",
 				},
+				cli.StringFlag{
+					Name:  "output, o",
+					Value: "table",
+					Usage: "output format: table, yaml or json",
+				},
+				cli.StringFlag{
+					Name:  "field-selector",
+					Value: "",
+					Usage: "select the resources by the value of their fields, e.g. status=up,tier!=0. Fields are the json names of the API, nested with dots (e.g. prices.cpu.unit_price); region.tier follows region_id",
+				},
+				cli.StringFlag{
+					Name:  "selector, l",
+					Value: "",
+					Usage: "select the resources with the syntax of the Kubernetes label selectors over their fields, e.g. 'architecture in (arm64,aarch64),region.tier=2,!description'",
+				},
+				cli.StringFlag{
+					Name:  "sort-by",
+					Value: "",
+					Usage: "field the resources are sorted by, e.g. tier or cpu_available",
+				},
+				cli.IntFlag{
+					Name:  "limit",
+					Value: 0,
+					Usage: "maximum number of resources printed (0 for no limit)",
+				},
 			},
 			SkipFlagParsing: false,
 			HideHelp:        false,
@@ -109,6 +139,38 @@ func main() {
 			Action: func(c *cli.Context) error {
 				return handleGet(c)
 			},
+			BashComplete: completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:      "describe",
+			Usage:     "show a resource together with the related ones",
+			ArgsUsage: "{regions|nodes|deployments|applications} [ID...]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "endpoint",
+					Value: api.DefaultEndpoint,
+					Usage: "API endpoint",
+				},
+				cli.StringFlag{
+					Name:  "id",
+					Value: "",
+					Usage: "identifier of the resource to be described (identifiers can also be given as arguments)",
+				},
+				cli.StringFlag{
+					Name:  "output, o",
+					Value: "table",
+					Usage: "output format: table, yaml or json",
+				},
+			},
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl describe",
+			Action:          handleDescribe,
+			BashComplete:    completeCommand,
 			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
 				return err
 			},
@@ -116,22 +178,22 @@ func main() {
 		cli.Command{
 			Name:      "put",
 			Usage:     "create/update a resource",
-			ArgsUsage: "{applications|deployments|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes}",
+			ArgsUsage: "{applications|deployments|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes} [ID]",
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "endpoint",
-					Value: "127.0.0.1:8080",
+					Value: api.DefaultEndpoint,
 					Usage: "API endpoint",
 				},
 				cli.StringFlag{
 					Name:  "id",
 					Value: "",
-					Usage: "identifier of the resource to be created/updated",
+					Usage: "identifier of the resource to be created/updated (default: the id or name found in the file)",
 				},
 				cli.StringFlag{
-					Name:  "file",
+					Name:  "file, f",
 					Value: "",
-					Usage: "filename containing the resource to be created/updated in json format. Check FogAtlas API documentation.",
+					Usage: "filename containing the resource to be created/updated in json or yaml format (\"-\" for stdin), either plain or as a kind-tagged manifest. Check FogAtlas API documentation.",
 				},
 			},
 			SkipFlagParsing: false,
@@ -141,29 +203,39 @@ func main() {
 			Action: func(c *cli.Context) error {
 				return handlePut(c)
 			},
+			BashComplete: completeCommand,
 			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
 				return err
 			},
 		},
 		cli.Command{
 			Name:      "patch",
-			Usage:     "update a resource",
-			ArgsUsage: "{deployments}",
+			Usage:     "update fields of a resource with a merge or json patch, or the status of a deployment",
+			ArgsUsage: "{applications|deployments|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes} [ID...]",
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "endpoint",
-					Value: "127.0.0.1:8080",
+					Value: api.DefaultEndpoint,
 					Usage: "API endpoint",
 				},
 				cli.StringFlag{
 					Name:  "id",
 					Value: "",
-					Usage: "identifier of the resource to be created/updated",
+					Usage: "identifier of the resource to be updated (names can also be given as arguments)",
 				},
 				cli.StringFlag{
 					Name:  "status",
 					Value: "",
-					Usage: "value of the status. Check FogAtlas API documentation.",
+					Usage: "value of the status of a deployment. Check FogAtlas API documentation.",
+				},
+				cli.StringFlag{
+					Name:  "type",
+					Value: "merge",
+					Usage: "type of the patch: merge (RFC 7386, null removes a field) or json (RFC 6902)",
+				},
+				cli.StringFlag{
+					Name:  "patch, p",
+					Usage: "the patch in json or yaml, e.g. '{\"status\": \"down\"}' or '[{\"op\": \"replace\", \"path\": \"/tier\", \"value\": 2}]'",
 				},
 			},
 			SkipFlagParsing: false,
@@ -173,6 +245,57 @@ func main() {
 			Action: func(c *cli.Context) error {
 				return handlePatch(c)
 			},
+			BashComplete: completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:      "set",
+			Usage:     "set fields of a resource",
+			ArgsUsage: "{applications|deployments|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes} [ID...] FIELD=VALUE...",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "endpoint",
+					Value: api.DefaultEndpoint,
+					Usage: "API endpoint",
+				},
+				cli.StringFlag{
+					Name:  "id",
+					Usage: "identifier of the resource to be updated (identifiers can also be given as arguments)",
+				},
+			},
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl set",
+			Action:          handleSet,
+			BashComplete:    completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:      "edit",
+			Usage:     "edit a resource in $EDITOR as yaml and update it",
+			ArgsUsage: "{applications|deployments|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes} [ID]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "endpoint",
+					Value: api.DefaultEndpoint,
+					Usage: "API endpoint",
+				},
+				cli.StringFlag{
+					Name:  "id",
+					Usage: "identifier of the resource to be edited (it can also be given as argument)",
+				},
+			},
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl edit",
+			Action:          handleEdit,
+			BashComplete:    completeCommand,
 			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
 				return err
 			},
@@ -180,17 +303,30 @@ func main() {
 		cli.Command{
 			Name:      "delete",
 			Usage:     "delete a resource",
-			ArgsUsage: "{applications|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes|deployments}",
+			ArgsUsage: "{applications|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes|deployments} [ID...]",
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "endpoint",
-					Value: "127.0.0.1:8080",
+					Value: api.DefaultEndpoint,
 					Usage: "API endpoint",
 				},
 				cli.StringFlag{
 					Name:  "id",
 					Value: "",
-					Usage: "identifier of the resource to be deleted",
+					Usage: "identifier of the resource to be deleted (identifiers can also be given as arguments)",
+				},
+				cli.StringFlag{
+					Name:  "cascade",
+					Value: "none",
+					Usage: "foreground deletes the dependent resources first (e.g. the nodes of a region), orphan leaves them, none does not look for them. foreground and orphan print the dependents and ask for confirmation",
+				},
+				cli.BoolFlag{
+					Name:  "yes, y",
+					Usage: "do not ask for confirmation",
+				},
+				cli.BoolFlag{
+					Name:  "force-protected",
+					Usage: "delete the resources even if they are protected (see fogatlasctl protect)",
 				},
 			},
 			SkipFlagParsing: false,
@@ -200,6 +336,7 @@ func main() {
 			Action: func(c *cli.Context) error {
 				return handleDelete(c)
 			},
+			BashComplete: completeCommand,
 			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
 				return err
 			},
@@ -208,23 +345,383 @@ func main() {
 			Name:      "putAll",
 			Usage:     "create/update a set of resources",
 			ArgsUsage: "{}",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "endpoint",
+					Value: api.DefaultEndpoint,
+					Usage: "API endpoint",
+				},
+				cli.StringSliceFlag{
+					Name:  "file, f",
+					Usage: "yaml file, directory or glob pattern that describes the resources to be loaded (\"-\" for stdin). Can be repeated",
+				},
+				cli.BoolFlag{
+					Name:  "atomic",
+					Usage: "roll back all the changes if one of the resources cannot be loaded",
+				},
+			}, templateFlags...),
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl putAll",
+			Action:          handlePutAll,
+			BashComplete:    completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:      "convert",
+			Usage:     "convert resources between the grouped format of putAll and kind-tagged manifests",
+			ArgsUsage: "{}",
+			Flags: append([]cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "file, f",
+					Usage: "yaml file, directory or glob pattern that describes the resources to be converted (\"-\" for stdin). Can be repeated",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Value: "kind",
+					Usage: "output format: kind or grouped",
+				},
+			}, templateFlags...),
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl convert",
+			Action:          handleConvert,
+			BashComplete:    completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:      "render",
+			Usage:     "print the resources that putAll would load, after rendering templates and overlays",
+			ArgsUsage: "{}",
+			Flags: append([]cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "file, f",
+					Usage: "yaml file, directory or glob pattern that describes the resources to be rendered (\"-\" for stdin). Can be repeated",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Value: "grouped",
+					Usage: "output format: grouped or kind",
+				},
+			}, templateFlags...),
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl render",
+			Action:          handleConvert,
+			BashComplete:    completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:     "deployment",
+			Usage:    "helpers to write deployment requests",
+			HideHelp: false,
+			Hidden:   false,
+			HelpName: "fogatlasctl deployment",
+			Subcommands: []cli.Command{
+				cli.Command{
+					Name:      "build",
+					Usage:     "create a deployment skeleton from Kubernetes manifests",
+					ArgsUsage: "{}",
+					Flags: []cli.Flag{
+						cli.StringSliceFlag{
+							Name:  "from-manifests",
+							Usage: "yaml file, directory or glob pattern with the Kubernetes Deployments of the microservices (\"-\" for stdin, e.g. the output of helm template). Can be repeated",
+						},
+						cli.StringFlag{
+							Name:  "name",
+							Value: "deployment",
+							Usage: "name of the deployment",
+						},
+						cli.StringFlag{
+							Name:  "description",
+							Value: "",
+							Usage: "description of the deployment",
+						},
+						cli.StringFlag{
+							Name:  "externalendpoint_id",
+							Value: "EXTERNAL_ENDPOINT_ID",
+							Usage: "identifier of the external endpoint the first dataflow starts from",
+						},
+						cli.Int64Flag{
+							Name:  "bandwidth_required",
+							Value: 1000000,
+							Usage: "bandwidth required by the placeholder dataflows",
+						},
+						cli.Int64Flag{
+							Name:  "latency_required",
+							Value: 100,
+							Usage: "latency required by the placeholder dataflows",
+						},
+					},
+					SkipFlagParsing: false,
+					HideHelp:        false,
+					Hidden:          false,
+					HelpName:        "fogatlasctl deployment build",
+					Action:          handleDeploymentBuild,
+					BashComplete:    completeCommand,
+					OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+						return err
+					},
+				},
+			},
+		},
+		cli.Command{
+			Name:      "validate",
+			Usage:     "check resources locally, without sending them",
+			ArgsUsage: "[applications|deployments|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes]",
+			Flags: append([]cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "file, f",
+					Usage: "yaml file, directory or glob pattern that describes the resources to be checked (\"-\" for stdin). Can be repeated. With a resource type, each file contains a single resource as for put",
+				},
+			}, templateFlags...),
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl validate",
+			Action:          handleValidate,
+			BashComplete:    completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:      "doctor",
+			Usage:     "check the references between the resources and their capacities",
+			ArgsUsage: "{}",
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "endpoint",
-					Value: "127.0.0.1:8080",
+					Value: api.DefaultEndpoint,
 					Usage: "API endpoint",
 				},
+				cli.BoolFlag{
+					Name:  "fix",
+					Usage: "suggest a command fixing each problem (nothing is changed)",
+				},
 				cli.StringFlag{
-					Name:  "file",
-					Value: "",
-					Usage: "yaml file that describes the resources to be loaded",
+					Name:  "output, o",
+					Value: "table",
+					Usage: "output format: table, yaml or json",
 				},
 			},
 			SkipFlagParsing: false,
 			HideHelp:        false,
 			Hidden:          false,
-			HelpName:        "fogatlasctl putAll",
-			Action: handlePutAll,
+			HelpName:        "fogatlasctl doctor",
+			Action:          handleDoctor,
+			BashComplete:    completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:      "ui",
+			Usage:     "browse the resources in a full-screen terminal interface",
+			ArgsUsage: "[regions|nodes|microservices|deployments|applications|relationships|externalendpoints|dynamicnodes]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "endpoint",
+					Value: api.DefaultEndpoint,
+					Usage: "API endpoint",
+				},
+				cli.IntFlag{
+					Name:  "refresh",
+					Value: 5,
+					Usage: "seconds between two refreshes of the list (0 disables the refresh)",
+				},
+			},
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl ui",
+			Action:          handleUI,
+			BashComplete:    completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:            "completion",
+			Usage:           "print the script that loads the shell completion",
+			ArgsUsage:       "{bash|zsh|fish}",
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl completion",
+			Action:          handleCompletion,
+			BashComplete:    completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:      "history",
+			Usage:     "show the changes made with fogatlasctl, recorded in the audit log",
+			ArgsUsage: "[applications|deployments|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes] [ID...]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "user",
+					Usage: "show only the changes made by this user",
+				},
+				cli.StringFlag{
+					Name:  "context",
+					Usage: "show only the changes sent to this API endpoint",
+				},
+				cli.StringFlag{
+					Name:  "command",
+					Usage: "show only the changes made by this command, e.g. putAll",
+				},
+				cli.StringFlag{
+					Name:  "since",
+					Usage: "show only the changes made in the given duration (e.g. 2h) or since the given time (e.g. 2019-06-01T10:00:00Z)",
+				},
+				cli.BoolFlag{
+					Name:  "failed",
+					Usage: "show only the requests that failed",
+				},
+				cli.IntFlag{
+					Name:  "limit",
+					Usage: "maximum number of changes printed, the most recent ones (0 for no limit)",
+				},
+				cli.StringFlag{
+					Name:  "output, o",
+					Value: "table",
+					Usage: "output format: table, yaml or json",
+				},
+			},
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl history",
+			Action:          handleHistory,
+			BashComplete:    completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:      "undo",
+			Usage:     "restore the resources changed by the last commands, as recorded in the audit log",
+			ArgsUsage: "{}",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "endpoint",
+					Value: api.DefaultEndpoint,
+					Usage: "API endpoint",
+				},
+				cli.IntFlag{
+					Name:  "steps",
+					Value: 1,
+					Usage: "number of commands undone, the most recent first",
+				},
+				cli.BoolFlag{
+					Name:  "yes, y",
+					Usage: "do not ask for confirmation",
+				},
+				cli.BoolFlag{
+					Name:  "force-protected",
+					Usage: "undo the changes even if the resources are protected (see fogatlasctl protect)",
+				},
+			},
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl undo",
+			Action:          handleUndo,
+			BashComplete:    completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:      "protect",
+			Usage:     "protect resources from delete, deleteAll and undo, or list the protections",
+			ArgsUsage: "[{applications|deployments|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes|*} [ID-PATTERN]]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "context",
+					Usage: "API endpoint (or glob pattern of endpoints) the protection applies to (default: all)",
+				},
+			},
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl protect",
+			Action:          handleProtect,
+			BashComplete:    completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:      "unprotect",
+			Usage:     "remove a protection, given as it was added",
+			ArgsUsage: "{applications|deployments|microservices|nodes|regions|relationships|externalendpoints|dynamicnodes|*} [ID-PATTERN]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "context",
+					Usage: "API endpoint (or glob pattern of endpoints) the protection applies to (default: all)",
+				},
+			},
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl unprotect",
+			Action:          handleUnprotect,
+			BashComplete:    completeCommand,
+			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+				return err
+			},
+		},
+		cli.Command{
+			Name:      "mock-server",
+			Usage:     "serve the FogAtlas API from memory, to try commands and scripts offline",
+			ArgsUsage: " ",
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "seed, f",
+					Usage: "yaml file, directory or glob pattern that describes the resources served at start, as for putAll. Can be repeated",
+				},
+				cli.StringFlag{
+					Name:  "listen",
+					Value: ":8080",
+					Usage: "address the server listens on",
+				},
+				cli.DurationFlag{
+					Name:  "latency",
+					Usage: "delay added to every response (e.g. 200ms)",
+				},
+				cli.Float64Flag{
+					Name:  "error-rate",
+					Usage: "fraction of the requests, between 0 and 1, failing with --error-status",
+				},
+				cli.IntFlag{
+					Name:  "error-status",
+					Value: 500,
+					Usage: "HTTP status of the failures injected with --error-rate",
+				},
+				cli.DurationFlag{
+					Name:  "transition-delay",
+					Value: 2 * time.Second,
+					Usage: "time a deployment spends in each status (todeploy, deploying, deployed, toundeploy, undeploying, undeployed)",
+				},
+			},
+			SkipFlagParsing: false,
+			HideHelp:        false,
+			Hidden:          false,
+			HelpName:        "fogatlasctl mock-server",
+			Action:          handleMockServer,
+			BashComplete:    completeCommand,
 			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
 				return err
 			},
@@ -236,1094 +733,416 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "endpoint",
-					Value: "127.0.0.1:8080",
+					Value: api.DefaultEndpoint,
 					Usage: "API endpoint",
 				},
+				cli.BoolFlag{
+					Name:  "force-protected",
+					Usage: "delete the resources even if they are protected (see fogatlasctl protect)",
+				},
 			},
 			SkipFlagParsing: false,
 			HideHelp:        false,
 			Hidden:          false,
 			HelpName:        "fogatlasctl deleteAll",
-			Action: handleDeleteAll,
+			Action:          handleDeleteAll,
+			BashComplete:    completeCommand,
 			OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
 				return err
 			},
 		},
 	}
-	err := app.Run(os.Args)
+	return app
+}
+
+// resourceArgs returns the resource type and the identifiers given to a
+// command, as in "fogatlasctl get node node13 node21". The identifier given
+// with --id, if any, comes first.
+func resourceArgs(c *cli.Context) (*resource.Type, []string, error) {
+	rt, err := resource.Lookup(c.Args().First())
 	if err != nil {
-		fmt.Printf("%s\n", err)
+		return nil, nil, fmt.Errorf("Error: %s", err)
 	}
+	var ids []string
+	if c.String("id") != "" {
+		ids = append(ids, c.String("id"))
+	}
+	ids = append(ids, c.Args().Tail()...)
+	return rt, ids, nil
 }
 
 func handleGet(c *cli.Context) error {
-	var resp interface{}
-	var err error
-	schemes := []string{"http"}
-	transport := httptransport.New(c.String("endpoint"), "/api/v2.0.0", schemes)
-	client := apiclient.New(transport, strfmt.Default)
-	switch resource := c.Args().Get(0); resource {
-	case "applications":
-		if c.String("id") != "" {
-			params := operations.NewGetApplicationsIDParams()
-			params.ID = c.String("id")
-			resp, err = client.Operations.GetApplicationsID(params)
-			if err != nil {
-				return fmt.Errorf("Error: get applications failed: %s", err)
-			}
-		} else {
-			params := operations.NewGetApplicationsParams()
-			resp, err = client.Operations.GetApplications(params)
-			if err != nil {
-				return fmt.Errorf("Error: get applications failed: %s", err)
-			}
-		}
-	case "deployments":
-		if c.String("id") != "" {
-			params := operations.NewGetDeploymentsNameParams()
-			params.Name = c.String("id")
-			resp, err = client.Operations.GetDeploymentsName(params)
-			if err != nil {
-				return fmt.Errorf("Error: get deployments failed: %s", err)
-			}
-		} else {
-			params := operations.NewGetDeploymentsParams()
-			if c.String("status") != "" {
-				status := c.String("status")
-				params.Status = &status
-			}
-			resp, err = client.Operations.GetDeployments(params)
-			if err != nil {
-				return fmt.Errorf("Error: get deployments failed: %s", err)
-			}
-		}
-	case "microservices":
-		if c.String("id") != "" {
-			params := operations.NewGetMicroservicesIDParams()
-			params.ID = c.String("id")
-			resp, err = client.Operations.GetMicroservicesID(params)
-			if err != nil {
-				return fmt.Errorf("Error: get microservices failed: %s", err)
-			}
-		} else {
-			params := operations.NewGetMicroservicesParams()
-			if c.String("node_id") != "" {
-				node_id := c.String("node_id")
-				params.NodeID = &node_id
-			}
-			resp, err = client.Operations.GetMicroservices(params)
-			if err != nil {
-				return fmt.Errorf("Error: get microservices failed: %s", err)
-			}
-		}
-	case "nodes":
-		if c.String("id") != "" {
-			params := operations.NewGetNodesIDParams()
-			params.ID = c.String("id")
-			resp, err = client.Operations.GetNodesID(params)
-			if err != nil {
-				return fmt.Errorf("Error: get nodes failed: %s", err)
-			}
-		} else {
-			params := operations.NewGetNodesParams()
-			if c.String("region_id") != "" {
-				region_id := c.String("region_id")
-				params.RegionID = &region_id
-			}
-			resp, err = client.Operations.GetNodes(params)
-			if err != nil {
-				return fmt.Errorf("Error: get nodes failed: %s", err)
-			}
-		}
-	case "regions":
-		if c.String("id") != "" {
-			params := operations.NewGetRegionsIDParams()
-			params.ID = c.String("id")
-			resp, err = client.Operations.GetRegionsID(params)
-			if err != nil {
-				return fmt.Errorf("Error: get regions failed: %s", err)
-			}
-		} else {
-			params := operations.NewGetRegionsParams()
-			resp, err = client.Operations.GetRegions(params)
-			if err != nil {
-				return fmt.Errorf("Error: get regions failed: %s", err)
-			}
+	ctx := context.Background()
+	client := api.NewClient(c.String("endpoint"))
+	rt, ids, err := resourceArgs(c)
+	if err != nil {
+		return err
+	}
+	objs := []interface{}{}
+	if len(ids) == 0 {
+		filters := make(map[string]string)
+		for _, name := range []string{"region_id", "node_id", "status"} {
+			filters[name] = c.String(name)
 		}
-	case "relationships":
-		if c.String("id") != "" {
-			params := operations.NewGetRelationshipsIDParams()
-			params.ID = c.String("id")
-			resp, err = client.Operations.GetRelationshipsID(params)
-			if err != nil {
-				return fmt.Errorf("Error: get relationships failed: %s", err)
-			}
-		} else {
-			params := operations.NewGetRelationshipsParams()
-			if c.String("region_id") != "" {
-				region_id := c.String("region_id")
-				params.RegionID = &region_id
-			}
-			resp, err = client.Operations.GetRelationships(params)
-			if err != nil {
-				return fmt.Errorf("Error: get relationships failed: %s", err)
-			}
+		list, err := resource.List(ctx, client, rt.Name, filters)
+		if err != nil {
+			return fmt.Errorf("Error: get %s failed: %s", rt.Name, err)
 		}
-	case "externalendpoints":
-		if c.String("id") != "" {
-			params := operations.NewGetExternalendpointsIDParams()
-			params.ID = c.String("id")
-			resp, err = client.Operations.GetExternalendpointsID(params)
-			if err != nil {
-				return fmt.Errorf("Error: get external endpoints failed: %s", err)
-			}
-		} else {
-			params := operations.NewGetExternalendpointsParams()
-			if c.String("region_id") != "" {
-				region_id := c.String("region_id")
-				params.RegionID = &region_id
-			}
-			resp, err = client.Operations.GetExternalendpoints(params)
-			if err != nil {
-				return fmt.Errorf("Error: get external endpoints failed: %s", err)
-			}
+		objs = append(objs, list...)
+	}
+	for _, id := range ids {
+		obj, err := rt.Get(ctx, client, id)
+		if err != nil {
+			return fmt.Errorf("Error: get %s failed: %s", rt.Name, err)
 		}
-	case "dynamicnodes":
-		if c.String("id") != "" {
-			params := operations.NewGetDynamicnodesIDParams()
-			params.ID = c.String("id")
-			resp, err = client.Operations.GetDynamicnodesID(params)
-			if err != nil {
-				return fmt.Errorf("Error: get dynamicnodes failed: %s", err)
-			}
-		} else {
-			params := operations.NewGetDynamicnodesParams()
-			if c.String("region_id") != "" {
-				region_id := c.String("region_id")
-				params.RegionID = &region_id
+		objs = append(objs, obj)
+	}
+	objs, err = selectObjects(c, resource.NewResolver(ctx, client), objs)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c.String("output") == "table":
+		printer.Tables(os.Stdout, rt, objs)
+	case len(ids) == 0:
+		// same layout as the list returned by the API
+		return printObject(map[string]interface{}{rt.Name: objs}, c.String("output"))
+	default:
+		for i, obj := range objs {
+			if i > 0 && c.String("output") == "yaml" {
+				fmt.Println("---")
 			}
-			resp, err = client.Operations.GetDynamicnodes(params)
-			if err != nil {
-				return fmt.Errorf("Error: get dynamicnodes failed: %s", err)
+			if err := printObject(obj, c.String("output")); err != nil {
+				return err
 			}
 		}
-	default:
-		return fmt.Errorf("Error: resource specificed (%s) is unkwnown", resource)
 	}
-	printData(resp)
 	return nil
 }
 
-func handlePatch(c *cli.Context) error {
-	schemes := []string{"http"}
-	transport := httptransport.New(c.String("endpoint"), "/api/v2.0.0", schemes)
-	client := apiclient.New(transport, strfmt.Default)
-	switch resource := c.Args().Get(0); resource {
-	case "deployments":
-		if c.String("id") == "" || c.String("status") == "" {
-			return fmt.Errorf("Error: options --id and --status are required")
-		}
-		params := operations.NewPatchDeploymentsNameParams()
-
-		var stat models.PatchStatus
-		stat.Status = c.String("status")
-		params.PatchStatus = &stat
-		params.Name = c.String("id")
-		resp, err := client.Operations.PatchDeploymentsName(params)
+// selectObjects keeps the resources matching --field-selector and --selector,
+// sorted by --sort-by and at most --limit of them.
+func selectObjects(c *cli.Context, resolver *resource.Resolver, objs []interface{}) ([]interface{}, error) {
+	var sel selector.Selector
+	if c.String("field-selector") != "" {
+		fieldSel, err := selector.ParseFields(c.String("field-selector"))
 		if err != nil {
-			return fmt.Errorf("Error: patch deployments failed (%s)", err)
+			return nil, fmt.Errorf("Error: %s", err)
 		}
-		fmt.Printf("%s\n", resp.Error())
-	default:
-		return fmt.Errorf("Error: resource specificed (%s) is unkwnown", resource)
+		sel = append(sel, fieldSel...)
 	}
-	return nil
-}
-
-func handlePut(c *cli.Context) error {
-	schemes := []string{"http"}
-	transport := httptransport.New(c.String("endpoint"), "/api/v2.0.0", schemes)
-	client := apiclient.New(transport, strfmt.Default)
-	switch resource := c.Args().Get(0); resource {
-	case "applications":
-		if c.String("id") == "" || c.String("file") == "" {
-			return fmt.Errorf("Error: options --id and --file are required")
-		}
-		params := operations.NewPutApplicationsIDParams()
-		str, err := getFromFile(c.String("file"))
-		if err != nil {
-			return fmt.Errorf("Error: unable to read file %s: %s", c.String("file"), err)
-		}
-		var d models.Application
-		b := []byte(str)
-		err = json.Unmarshal(b, &d)
-		if err != nil {
-			return fmt.Errorf("Error: wrong file format: %s", err)
-		}
-		params.Application = &d
-		params.ID = c.String("id")
-		resp, err := client.Operations.PutApplicationsID(params)
-		if err != nil {
-			return fmt.Errorf("Error: put applications failed (%s)", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "deployments":
-		if c.String("id") == "" || c.String("file") == "" {
-			return fmt.Errorf("Error: options --id and --file are required")
-		}
-		params := operations.NewPutDeploymentsNameParams()
-		str, err := getFromFile(c.String("file"))
+	if c.String("selector") != "" {
+		labelSel, err := selector.Parse(c.String("selector"))
 		if err != nil {
-			return fmt.Errorf("Error: unable to read file %s: %s", c.String("file"), err)
+			return nil, fmt.Errorf("Error: %s", err)
 		}
-		var d models.Deployment
-		b := []byte(str)
-		err = json.Unmarshal(b, &d)
-		if err != nil {
-			return fmt.Errorf("Error: wrong file format: %s", err)
-		}
-		params.Deployment = &d
-		params.Name = c.String("id")
-		resp, err := client.Operations.PutDeploymentsName(params)
-		if err != nil {
-			return fmt.Errorf("Error: put deployments failed (%s)", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
+		sel = append(sel, labelSel...)
+	}
 
-	case "microservices":
-		if c.String("id") == "" || c.String("file") == "" {
-			return fmt.Errorf("Error: options --id and --file are required")
+	selected := []interface{}{}
+	for _, obj := range objs {
+		if sel.Matches(func(path string) []string { return resolver.Field(obj, path) }) {
+			selected = append(selected, obj)
 		}
-		params := operations.NewPutMicroservicesIDParams()
-		str, err := getFromFile(c.String("file"))
-		if err != nil {
-			return fmt.Errorf("Error: unable to read file %s: %s", c.String("file"), err)
-		}
-		var d models.Microservice
-		b := []byte(str)
-		err = json.Unmarshal(b, &d)
-		if err != nil {
-			return fmt.Errorf("Error: wrong file format: %s", err)
-		}
-		params.Microservice = &d
-		params.ID = c.String("id")
-		resp, err := client.Operations.PutMicroservicesID(params)
-		if err != nil {
-			return fmt.Errorf("Error: put microservices failed (%s)", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "nodes":
-		if c.String("id") == "" || c.String("file") == "" {
-			return fmt.Errorf("Error: options --id and --file are required")
-		}
-		params := operations.NewPutNodesIDParams()
-		str, err := getFromFile(c.String("file"))
-		if err != nil {
-			return fmt.Errorf("Error: unable to read file %s: %s", c.String("file"), err)
-		}
-		var d models.Node
-		b := []byte(str)
-		err = json.Unmarshal(b, &d)
-		if err != nil {
-			return fmt.Errorf("Error: wrong file format: %s", err)
-		}
-		params.Node = &d
-		params.ID = c.String("id")
-		resp, err := client.Operations.PutNodesID(params)
-		if err != nil {
-			return fmt.Errorf("Error: put nodes failed (%s)", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "regions":
-		if c.String("id") == "" || c.String("file") == "" {
-			return fmt.Errorf("Error: options --id and --file are required")
-		}
-		params := operations.NewPutRegionsIDParams()
-		str, err := getFromFile(c.String("file"))
-		if err != nil {
-			return fmt.Errorf("Error: unable to read file %s: %s", c.String("file"), err)
-		}
-		var d models.Region
-		b := []byte(str)
-		err = json.Unmarshal(b, &d)
-		if err != nil {
-			return fmt.Errorf("Error: wrong file format: %s", err)
-		}
-		params.Region = &d
-		params.ID = c.String("id")
-		resp, err := client.Operations.PutRegionsID(params)
-		if err != nil {
-			return fmt.Errorf("Error: put regions failed (%s)", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "relationships":
-		if c.String("id") == "" || c.String("file") == "" {
-			return fmt.Errorf("Error: options --id and --file are required")
-		}
-		params := operations.NewPutRelationshipsIDParams()
-		str, err := getFromFile(c.String("file"))
-		if err != nil {
-			return fmt.Errorf("Error: unable to read file %s: %s", c.String("file"), err)
-		}
-		var d models.Relationship
-		b := []byte(str)
-		err = json.Unmarshal(b, &d)
-		if err != nil {
-			return fmt.Errorf("Error: wrong file format: %s", err)
-		}
-		params.Relationship = &d
-		params.ID = c.String("id")
-		resp, err := client.Operations.PutRelationshipsID(params)
-		if err != nil {
-			return fmt.Errorf("Error: put relationships failed (%s)", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "externalendpoints":
-		if c.String("id") == "" || c.String("file") == "" {
-			return fmt.Errorf("Error: options --id and --file are required")
-		}
-		params := operations.NewPutExternalendpointsIDParams()
-		str, err := getFromFile(c.String("file"))
-		if err != nil {
-			return fmt.Errorf("Error: unable to read file %s: %s", c.String("file"), err)
-		}
-		var d models.ExternalEndpoint
-		b := []byte(str)
-		err = json.Unmarshal(b, &d)
-		if err != nil {
-			return fmt.Errorf("Error: wrong file format: %s", err)
-		}
-		params.Externalendpoint = &d
-		params.ID = c.String("id")
-		resp, err := client.Operations.PutExternalendpointsID(params)
-		if err != nil {
-			return fmt.Errorf("Error: put external endpoints failed (%s)", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "dynamicnodes":
-		if c.String("id") == "" || c.String("file") == "" {
-			return fmt.Errorf("Error: options --id and --file are required")
-		}
-		params := operations.NewPutDynamicnodesIDParams()
-		str, err := getFromFile(c.String("file"))
-		if err != nil {
-			return fmt.Errorf("Error: unable to read file %s: %s", c.String("file"), err)
-		}
-		var d models.DynamicNode
-		b := []byte(str)
-		err = json.Unmarshal(b, &d)
-		if err != nil {
-			return fmt.Errorf("Error: wrong file format: %s", err)
-		}
-		params.Dynamicnode = &d
-		params.ID = c.String("id")
-		resp, err := client.Operations.PutDynamicnodesID(params)
-		if err != nil {
-			return fmt.Errorf("Error: put dynamicnodes failed (%s)", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	default:
-		return fmt.Errorf("Error: resource specificed (%s) is unkwnown", resource)
 	}
-	return nil
+	if c.String("sort-by") != "" {
+		resource.SortBy(selected, strings.TrimPrefix(c.String("sort-by"), "."), resolver.Field)
+	}
+	if err := resolver.Err(); err != nil {
+		return nil, fmt.Errorf("Error: %s", err)
+	}
+	if c.Int("limit") < 0 {
+		return nil, fmt.Errorf("Error: option --limit must not be negative")
+	}
+	if c.Int("limit") > 0 && len(selected) > c.Int("limit") {
+		selected = selected[:c.Int("limit")]
+	}
+	return selected, nil
 }
 
-func handleDelete(c *cli.Context) error {
-	schemes := []string{"http"}
-	transport := httptransport.New(c.String("endpoint"), "/api/v2.0.0", schemes)
-	client := apiclient.New(transport, strfmt.Default)
-	switch resource := c.Args().Get(0); resource {
-	case "applications":
-		if c.String("id") == "" {
-			return fmt.Errorf("Error: option --id is required")
-		}
-		params := operations.NewDeleteApplicationsIDParams()
-		params.ID = c.String("id")
-		resp, err := client.Operations.DeleteApplicationsID(params)
-		if err != nil {
-			return fmt.Errorf("Error: delete applications failed: %s", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "microservices":
-		if c.String("id") == "" {
-			return fmt.Errorf("Error: option --id is required")
-		}
-		params := operations.NewDeleteMicroservicesIDParams()
-		params.ID = c.String("id")
-		resp, err := client.Operations.DeleteMicroservicesID(params)
-		if err != nil {
-			return fmt.Errorf("Error: delete microservices failed: %s", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "nodes":
-		if c.String("id") == "" {
-			return fmt.Errorf("Error: option --id is required")
-		}
-		params := operations.NewDeleteNodesIDParams()
-		params.ID = c.String("id")
-		resp, err := client.Operations.DeleteNodesID(params)
-		if err != nil {
-			return fmt.Errorf("Error: delete nodes failed: %s", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "regions":
-		if c.String("id") == "" {
-			return fmt.Errorf("Error: option --id is required")
-		}
-		params := operations.NewDeleteRegionsIDParams()
-		params.ID = c.String("id")
-		resp, err := client.Operations.DeleteRegionsID(params)
-		if err != nil {
-			return fmt.Errorf("Error: delete regions failed: %s", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "relationships":
-		if c.String("id") == "" {
-			return fmt.Errorf("Error: option --id is required")
-		}
-		params := operations.NewDeleteRelationshipsIDParams()
-		params.ID = c.String("id")
-		resp, err := client.Operations.DeleteRelationshipsID(params)
-		if err != nil {
-			return fmt.Errorf("Error: delete relationships failed: %s", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "external endpoints":
-		if c.String("id") == "" {
-			return fmt.Errorf("Error: option --id is required")
-		}
-		params := operations.NewDeleteExternalendpointsIDParams()
-		params.ID = c.String("id")
-		resp, err := client.Operations.DeleteExternalendpointsID(params)
-		if err != nil {
-			return fmt.Errorf("Error: delete external endpoints failed: %s", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "dynamicnodes":
-		if c.String("id") == "" {
-			return fmt.Errorf("Error: option --id is required")
-		}
-		params := operations.NewDeleteDynamicnodesIDParams()
-		params.ID = c.String("id")
-		resp, err := client.Operations.DeleteDynamicnodesID(params)
-		if err != nil {
-			return fmt.Errorf("Error: delete dynamicnodes failed: %s", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	case "deployments":
-		if c.String("id") == "" {
-			return fmt.Errorf("Error: option --id is required")
-		}
-		params := operations.NewDeleteDeploymentsNameParams()
-		params.Name = c.String("id")
-		resp, err := client.Operations.DeleteDeploymentsName(params)
-		if err != nil {
-			return fmt.Errorf("Error: delete deployment failed: %s", err)
-		}
-		fmt.Printf("%s\n", resp.Error())
-	default:
-		return fmt.Errorf("Error: resource specificed (%s) is unkwnown", resource)
+// printObject prints a model, or any value made of models, on stdout in yaml
+// or json format.
+func printObject(obj interface{}, format string) error {
+	if err := printer.Object(os.Stdout, obj, format); err != nil {
+		return fmt.Errorf("Error: %s", err)
 	}
 	return nil
 }
 
-func handlePutAll(c *cli.Context) error {
-	if c.String("file") == "" {
-		return fmt.Errorf("Error: option --file is required")
-	}
-
-	schemes := []string{"http"}
-	transport := httptransport.New(c.String("endpoint"), "/api/v2.0.0", schemes)
-	client := apiclient.New(transport, strfmt.Default)
-
-	conf := &confFile{}
-	if err := parseYAML(c.String("file"), conf); err != nil {
+func handlePatch(c *cli.Context) error {
+	client := newClient(c)
+	rt, ids, err := resourceArgs(c)
+	if err != nil {
 		return err
 	}
-
-	for _, app := range conf.Applications {
-		params := operations.NewPutApplicationsIDParams()
-		params.Application = &app
-		params.ID = app.ID
-		resp, err := client.Operations.PutApplicationsID(params)
-		if err != nil {
-			fmt.Printf("error while sending request: %s", err)
-			continue
-		}
-		if resp != nil {
-			fmt.Println(resp.Error())
-		}
+	if len(ids) == 0 {
+		return fmt.Errorf("Error: an identifier (as argument or with --id) is required")
 	}
-
-	for _, reg := range conf.Regions {
-		params := operations.NewPutRegionsIDParams()
-		params.Region = &reg
-		params.ID = reg.ID
-		resp, err := client.Operations.PutRegionsID(params)
-		if err != nil {
-			fmt.Printf("error while sending request: %s", err)
-			continue
+	if c.String("status") != "" {
+		if rt.Name != "deployments" {
+			return fmt.Errorf("Error: option --status is valid only for deployments")
 		}
-		if resp != nil {
-			fmt.Println(resp.Error())
+		for _, id := range ids {
+			msg, err := resource.PatchDeploymentStatus(context.Background(), client, id, c.String("status"))
+			if err != nil {
+				return fmt.Errorf("Error: patch deployments failed (%s)", err)
+			}
+			fmt.Printf("%s\n", msg)
 		}
+		return nil
 	}
 
-	for _, depl := range conf.Deployments {
-		params := operations.NewPutDeploymentsNameParams()
-		params.Deployment = &depl
-		params.Name = depl.Name
-		resp, err := client.Operations.PutDeploymentsName(params)
-		if err != nil {
-			fmt.Printf("error while sending request: %s", err)
-			continue
-		}
-		if resp != nil {
-			fmt.Println(resp.Error())
-		}
+	if c.String("patch") == "" {
+		return fmt.Errorf("Error: option --patch is required")
 	}
-
-	for _, ms := range conf.Microservices {
-		params := operations.NewPutMicroservicesIDParams()
-		params.Microservice = &ms
-		params.ID = params.Microservice.Name
-		resp, err := client.Operations.PutMicroservicesID(params)
-		if err != nil {
-			fmt.Printf("error while sending request: %s", err)
-			continue
-		}
-		if resp != nil {
-			fmt.Println(resp.Error())
-		}
+	// json is valid yaml, so both formats are accepted
+	data, err := yaml.YAMLToJSON([]byte(c.String("patch")))
+	if err != nil {
+		return fmt.Errorf("Error: wrong patch format: %s", err)
 	}
-
-	for _, node := range conf.Nodes {
-		params := operations.NewPutNodesIDParams()
-		params.Node = &node
-		params.ID = node.ID
-		resp, err := client.Operations.PutNodesID(params)
-		if err != nil {
-			fmt.Printf("error while sending request: %s", err)
-			continue
-		}
-		if resp != nil {
-			fmt.Println(resp.Error())
-		}
+	var change update.Change
+	switch c.String("type") {
+	case "merge":
+		var patch map[string]interface{}
+		if err := json.Unmarshal(data, &patch); err != nil {
+			return fmt.Errorf("Error: a merge patch must be an object: %s", err)
+		}
+		change = update.MergePatch(patch)
+	case "json":
+		var ops []update.Operation
+		if err := json.Unmarshal(data, &ops); err != nil {
+			return fmt.Errorf("Error: a json patch must be a list of operations: %s", err)
+		}
+		change = update.JSONPatch(ops)
+	default:
+		return fmt.Errorf("Error: patch type specified (%s) is unknown, use merge or json", c.String("type"))
 	}
+	return updateResources(c, client, rt, ids, change)
+}
 
-	for _, rel := range conf.Relationships {
-		params := operations.NewPutRelationshipsIDParams()
-		params.Relationship = &rel
-		params.ID = rel.ID
-		resp, err := client.Operations.PutRelationshipsID(params)
-		if err != nil {
-			fmt.Printf("error while sending request: %s", err)
-			continue
-		}
-		if resp != nil {
-			fmt.Println(resp.Error())
-		}
+func handleSet(c *cli.Context) error {
+	client := newClient(c)
+	rt, args, err := resourceArgs(c)
+	if err != nil {
+		return err
 	}
-
-	for _, ee := range conf.ExternalEdpoints {
-		params := operations.NewPutExternalendpointsIDParams()
-		params.Externalendpoint = &ee
-		params.ID = ee.ID
-		resp, err := client.Operations.PutExternalendpointsID(params)
-		if err != nil {
-			fmt.Printf("error while sending request: %s", err)
-			continue
-		}
-		if resp != nil {
-			fmt.Println(resp.Error())
+	var ids, assignments []string
+	for _, arg := range args {
+		if strings.Contains(arg, "=") {
+			assignments = append(assignments, arg)
+		} else {
+			ids = append(ids, arg)
 		}
 	}
+	if len(ids) == 0 || len(assignments) == 0 {
+		return fmt.Errorf("Error: an identifier (as argument or with --id) and at least a field=value are required")
+	}
+	change, err := update.Set(rt.Name, assignments)
+	if err != nil {
+		return fmt.Errorf("Error: %s", err)
+	}
+	return updateResources(c, client, rt, ids, change)
+}
 
-	for _, dnode := range conf.DynamicNodes {
-		params := operations.NewPutDynamicnodesIDParams()
-		params.Dynamicnode = &dnode
-		params.ID = dnode.ID
-		resp, err := client.Operations.PutDynamicnodesID(params)
+// updateResources applies a change to each resource, see update.Update.
+func updateResources(c *cli.Context, client *operations.Client, rt *resource.Type, ids []string, change update.Change) error {
+	failed := 0
+	for _, id := range ids {
+		result, err := update.Update(context.Background(), client, rt.Name, id, change)
 		if err != nil {
-			fmt.Printf("error while sending request: %s", err)
+			fmt.Printf("Error: update %s %s failed: %s\n", rt.Name, id, err)
+			failed++
 			continue
 		}
-		if resp != nil {
-			fmt.Println(resp.Error())
+		if !result.Changed {
+			fmt.Printf("%s %s unchanged\n", rt.Name, id)
+			continue
 		}
+		fmt.Printf("%s\n", result.Message)
+	}
+	if failed > 0 {
+		return fmt.Errorf("Error: %d of %d updates failed", failed, len(ids))
 	}
-
 	return nil
 }
 
-func getFromFile(filename string) (string, error) {
-	data, err := ioutil.ReadFile(filename)
+func handlePut(c *cli.Context) error {
+	client := newClient(c)
+	rt, ids, err := resourceArgs(c)
 	if err != nil {
-		return "", err
+		return err
+	}
+	if len(ids) > 1 {
+		return fmt.Errorf("Error: put accepts a single identifier")
 	}
-	return string(data), nil
+	obj := rt.NewModel()
+	if c.String("file") == "" {
+		return fmt.Errorf("Error: option --file is required")
+	}
+	b, err := manifest.ReadInput(c.String("file"))
+	if err != nil {
+		return fmt.Errorf("Error: unable to read file %s: %s", c.String("file"), err)
+	}
+	// json is valid yaml, so both formats are accepted
+	b, err = yaml.YAMLToJSON(b)
+	if err != nil {
+		return fmt.Errorf("Error: wrong file format: %s", err)
+	}
+	b, err = manifest.ResolveDescriptors(b, filepath.Dir(c.String("file")))
+	if err != nil {
+		return fmt.Errorf("Error: wrong deployment descriptor: %s", err)
+	}
+	err = manifest.DecodeResource(b, rt.Name, obj)
+	if err != nil {
+		return fmt.Errorf("Error: wrong file format: %s", err)
+	}
+	if depl, ok := obj.(*models.Deployment); ok {
+		if nerrs := validateDeployment(depl); nerrs > 0 {
+			return fmt.Errorf("Error: deployment not valid (%d errors)", nerrs)
+		}
+	}
+	id := rt.ID(obj)
+	if len(ids) == 1 {
+		id = ids[0]
+	}
+	if id == "" {
+		return fmt.Errorf("Error: an identifier (as argument or with --id) is required when the resource has no %s", rt.IDKey())
+	}
+	resp, err := rt.Put(context.Background(), client, id, obj)
+	if err != nil {
+		return fmt.Errorf("Error: put %s failed (%s)", rt.Name, err)
+	}
+	fmt.Printf("%s\n", resp)
+	return nil
 }
 
-func parseYAML(filename string, obj *confFile) error {
-	strData, err := ioutil.ReadFile(filename)
+func handleDelete(c *cli.Context) error {
+	client := newClient(c)
+	rt, ids, err := resourceArgs(c)
 	if err != nil {
 		return err
 	}
-	b := []byte(strData)
-	if err := yaml.Unmarshal(b, obj); err != nil {
+	if len(ids) == 0 {
+		return fmt.Errorf("Error: an identifier (as argument or with --id) is required")
+	}
+	switch c.String("cascade") {
+	case "none":
+	case "foreground", "orphan":
+		return deleteCascade(c, client, rt, ids)
+	default:
+		return fmt.Errorf("Error: cascade mode specified (%s) is unknown, use foreground, orphan or none", c.String("cascade"))
+	}
+	if err := checkProtected(c, protectedTrees(rt.Name, ids)); err != nil {
 		return err
 	}
-	return nil
-}
-
-func handleDeleteAll(c *cli.Context) error {
-	var resp interface{}
-	var err error
-	schemes := []string{"http"}
-	transport := httptransport.New(c.String("endpoint"), "/api/v2.0.0", schemes)
-	client := apiclient.New(transport, strfmt.Default)
-
-	switch 	resource := c.Args().Get(0); resource {
-	case "applications":
-		params := operations.NewGetApplicationsParams()
-		resp, err = client.Operations.GetApplications(params)
-		if err != nil {
-			return fmt.Errorf("Error: get applications failed: %s", err)
-		}
-		for _, app := range resp.(*operations.GetApplicationsOK).Payload.Applications {
-			params := operations.NewDeleteApplicationsIDParams()
-			params.ID = app.ID
-			resp, err := client.Operations.DeleteApplicationsID(params)
-			if err != nil {
-				return fmt.Errorf("Error: delete applications failed: %s", err)
-			}
-			fmt.Printf("%s\n", resp.Error())
-		}
-	case "deployments":
-		params := operations.NewGetDeploymentsParams()
-		resp, err = client.Operations.GetDeployments(params)
-		if err != nil {
-			return fmt.Errorf("Error: get deployments failed: %s", err)
-		}
-		for _, depl := range resp.(*operations.GetDeploymentsOK).Payload.Deployments {
-			params := operations.NewDeleteDeploymentsNameParams()
-			params.Name = depl.Name
-			resp, err := client.Operations.DeleteDeploymentsName(params)
-			if err != nil {
-				fmt.Printf("Error: delete deployment failed: %s", err)
-			}
-			fmt.Printf("%s\n", resp.Error())
-		}
-
-	case "microservices":
-		params := operations.NewGetMicroservicesParams()
-		resp, err = client.Operations.GetMicroservices(params)
+	failed := 0
+	for _, id := range ids {
+		msg, err := rt.Delete(context.Background(), client, id)
 		if err != nil {
-			return fmt.Errorf("Error: get microservices failed: %s", err)
-		}
-		for _, ms := range resp.(*operations.GetMicroservicesOK).Payload.Microservices {
-			params := operations.NewDeleteMicroservicesIDParams()
-			params.ID = ms.ID
-			resp, err := client.Operations.DeleteMicroservicesID(params)
-			if err != nil {
-				fmt.Printf("Error: delete microservices failed: %s", err)
-			}
-			fmt.Printf("%s\n", resp.Error())
-		}
-
-	case "nodes":
-		params := operations.NewGetNodesParams()
-		resp, err = client.Operations.GetNodes(params)
-		if err != nil {
-			return fmt.Errorf("Error: get nodes failed: %s", err)
-		}
-		for _, node := range resp.(*operations.GetNodesOK).Payload.Nodes {
-			params := operations.NewDeleteNodesIDParams()
-			params.ID = node.ID
-			resp, err := client.Operations.DeleteNodesID(params)
-			if err != nil {
-				fmt.Printf("Error: delete nodes failed: %s", err)
-			}
-			fmt.Printf("%s\n", resp.Error())
-		}
-
-	case "regions":
-		params := operations.NewGetRegionsParams()
-		resp, err = client.Operations.GetRegions(params)
-		if err != nil {
-			return fmt.Errorf("Error: get regions failed: %s", err)
-		}
-		for _, reg := range resp.(*operations.GetRegionsOK).Payload.Regions {
-			params := operations.NewDeleteRegionsIDParams()
-			params.ID = reg.ID
-			resp, err := client.Operations.DeleteRegionsID(params)
-			if err != nil {
-				fmt.Printf("Error: delete regions failed: %s", err)
-			}
-			fmt.Printf("%s\n", resp.Error())
-		}
-
-	case "relationships":
-		params := operations.NewGetRelationshipsParams()
-		resp, err = client.Operations.GetRelationships(params)
-		if err != nil {
-			return fmt.Errorf("Error: get relationships failed: %s", err)
-		}
-		for _, rel := range resp.(*operations.GetRelationshipsOK).Payload.Relationships {
-			params := operations.NewDeleteRelationshipsIDParams()
-			params.ID = rel.ID
-			resp, err := client.Operations.DeleteRelationshipsID(params)
-			if err != nil {
-				fmt.Printf("Error: delete relationships failed: %s", err)
-			}
-			fmt.Printf("%s\n", resp.Error())
-		}
-
-	case "externalendpoints":
-		params := operations.NewGetExternalendpointsParams()
-		resp, err = client.Operations.GetExternalendpoints(params)
-		if err != nil {
-			return fmt.Errorf("Error: get external endpoints failed: %s", err)
-		}
-		for _, ee := range resp.(*operations.GetExternalendpointsOK).Payload.Externalendpoints {
-			params := operations.NewDeleteExternalendpointsIDParams()
-			params.ID = ee.ID
-			resp, err := client.Operations.DeleteExternalendpointsID(params)
-			if err != nil {
-				fmt.Printf("Error: delete external endpoints failed: %s", err)
-			}
-			fmt.Printf("%s\n", resp.Error())
-		}
-
-	case "dynamicnodes":
-		params := operations.NewGetDynamicnodesParams()
-		if c.String("region_id") != "" {
-			region_id := c.String("region_id")
-			params.RegionID = &region_id
-		}
-		resp, err = client.Operations.GetDynamicnodes(params)
-		if err != nil {
-			return fmt.Errorf("Error: get dynamicnodes failed: %s", err)
-		}
-		for _, dnode := range resp.(*operations.GetDynamicnodesOK).Payload.Dynamicnodes {
-			params := operations.NewDeleteDynamicnodesIDParams()
-			params.ID = dnode.ID
-			resp, err := client.Operations.DeleteDynamicnodesID(params)
-			if err != nil {
-				fmt.Printf("Error: delete dynamicnodes failed: %s", err)
-			}
-			fmt.Printf("%s\n", resp.Error())
+			fmt.Printf("Error: delete %s %s failed: %s\n", rt.Name, id, err)
+			failed++
+			continue
 		}
-
-	default:
-		return fmt.Errorf("Error: resource specificed (%s) is unkwnown", resource)
+		fmt.Printf("%s\n", msg)
+	}
+	if failed > 0 {
+		return fmt.Errorf("Error: %d of %d deletions failed", failed, len(ids))
 	}
-
 	return nil
 }
 
-func printData(any interface{}) {
-	if resp, ok := any.(*operations.GetApplicationsOK); ok {
-		var data [][]string
-		for _, app := range resp.Payload.Applications {
-			msids := ""
-			for _, ms := range app.Microservices {
-				if msids == "" {
-					msids = ms.MicroserviceID
-				} else {
-					msids = msids + "," + ms.MicroserviceID
-				}
-			}
-			str := []string{app.ID, app.Name, app.Description, app.Status, msids}
-			data = append(data, str)
-		}
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "Name", "Description", "Status", "Microservice Id"})
-		table.AppendBulk(data)
-		table.Render()
-	}
-	if resp, ok := any.(*operations.GetApplicationsIDOK); ok {
-		var data [][]string
-		app := resp.Payload
-		msids := ""
-		for _, ms := range app.Microservices {
-			if msids == "" {
-				msids = ms.MicroserviceID
-			} else {
-				msids = msids + "," + ms.MicroserviceID
-			}
-		}
-		str := []string{app.ID, app.Name, app.Description, app.Status, msids}
-		data = append(data, str)
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "Name", "Description", "Status", "Microservice Id"})
-		table.AppendBulk(data)
-		table.Render()
-	}
-	if resp, ok := any.(*operations.GetDeploymentsOK); ok {
-		var data [][]string
-		var datams [][]string
-		var datadf [][]string
-		for _, depl := range resp.Payload.Deployments {
-			for _, ms := range depl.Microservices {
-				strms := []string{depl.Name, ms.Name, ms.Description, ms.CPURequired, ms.MemoryRequired, ms.DiskRequired,
-					ms.RegionID, ms.RegionRequired, strconv.FormatFloat(ms.PriceRequired, 'f', 2, 64),
-					strconv.FormatFloat(ms.PriceComputed, 'f', 2, 64), ms.DeploymentDescriptor}
-				datams = append(datams, strms)
-			}
-			for _, df := range depl.Dataflows {
-				strdf := []string{depl.Name, df.SourceID, df.DestinationID, strconv.FormatInt(df.BandwidthRequired, 10),
-					strconv.FormatInt(df.LatencyRequired, 10)}
-				datadf = append(datadf, strdf)
-			}
-			str := []string{depl.Name, depl.Description, depl.Status, depl.ExternalendpointID}
-			data = append(data, str)
-		}
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"Name", "Description", "Status", "ExternaEndpointID"})
-		table.AppendBulk(data)
-		table.Render()
-		fmt.Printf("Microservices Requirements\n")
-		table = tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"Depl. Name", "Name", "Description", "CPURequired", "MemoryRequired", "DiskRequired",
-			"RegionID", "RegionRequired", "PriceRequired", "PriceComputed", "Deployment Descriptor"})
-		table.AppendBulk(datams)
-		table.Render()
-		fmt.Printf("Dataflows\n")
-		table = tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"Depl. Name", "SourceID", "DestinationID", "BandwidthRequired", "LatencyRequired"})
-		table.AppendBulk(datadf)
-		table.Render()
+// deleteCascade prints the resources depending on the ones to be deleted and,
+// after confirmation, deletes them bottom-up (foreground) or leaves them
+// (orphan).
+func deleteCascade(c *cli.Context, client *operations.Client, rt *resource.Type, ids []string) error {
+	ctx := context.Background()
+	trees, err := cascade.Dependents(ctx, client, rt.Name, ids)
+	if err != nil {
+		return fmt.Errorf("Error: %s", err)
 	}
-	if resp, ok := any.(*operations.GetDeploymentsNameOK); ok {
-		var data [][]string
-		var datams [][]string
-		var datadf [][]string
-		depl := resp.Payload
-		for _, ms := range depl.Microservices {
-			strms := []string{depl.Name, ms.Name, ms.Description, ms.CPURequired, ms.MemoryRequired, ms.DiskRequired,
-				ms.RegionID, ms.RegionRequired, strconv.FormatFloat(ms.PriceRequired, 'f', 2, 64),
-				strconv.FormatFloat(ms.PriceComputed, 'f', 2, 64), ms.DeploymentDescriptor}
-			datams = append(datams, strms)
-		}
-		for _, df := range depl.Dataflows {
-			strdf := []string{depl.Name, df.SourceID, df.DestinationID, strconv.FormatInt(df.BandwidthRequired, 10),
-				strconv.FormatInt(df.LatencyRequired, 10)}
-			datadf = append(datadf, strdf)
-		}
-		str := []string{depl.Name, depl.Description, depl.Status, depl.ExternalendpointID}
-		data = append(data, str)
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"Name", "Description", "Status", "ExternalEndpointID"})
-		table.AppendBulk(data)
-		table.Render()
-		fmt.Printf("Microservices Requirements\n")
-		table = tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"Depl. Name", "Name", "Description", "CPURequired", "MemoryRequired", "DiskRequired",
-			"RegionID", "RegionRequired", "PriceRequired", "PriceComputed", "Deployment Descriptor"})
-		table.AppendBulk(datams)
-		table.Render()
-		fmt.Printf("Dataflows\n")
-		table = tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"Depl. Name", "SourceID", "DestinationID", "BandwidthRequired", "LatencyRequired"})
-		table.AppendBulk(datadf)
-		table.Render()
+	cascade.Print(os.Stdout, trees)
+
+	total := 0
+	for _, t := range trees {
+		total += t.Count()
 	}
-	if resp, ok := any.(*operations.GetMicroservicesOK); ok {
-		var data [][]string
-		for _, ms := range resp.Payload.Microservices {
-			str := []string{ms.ID, ms.Name, ms.Description, ms.ApplicationID, ms.NodeID, ms.RegionID, ms.Status}
-			data = append(data, str)
+	question := fmt.Sprintf("Delete these %d resources?", total)
+	if c.String("cascade") == "orphan" {
+		question = fmt.Sprintf("Delete %d %s and leave %d dependents?", len(ids), rt.Name, total-len(ids))
+		for _, t := range trees {
+			t.Children = nil
 		}
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "Name", "Description", "ApplicationID", "NodeID", "RegionID", "Status"})
-		table.AppendBulk(data)
-		table.Render()
 	}
-	if resp, ok := any.(*operations.GetMicroservicesIDOK); ok {
-		var data [][]string
-		ms := resp.Payload
-		str := []string{ms.ID, ms.Name, ms.Description, ms.ApplicationID, ms.NodeID, ms.RegionID, ms.Status}
-		data = append(data, str)
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "Name", "Description", "ApplicationID", "NodeID", "RegionID", "Status"})
-		table.AppendBulk(data)
-		table.Render()
+	if err := checkProtected(c, trees); err != nil {
+		return err
 	}
-	if resp, ok := any.(*operations.GetNodesOK); ok {
-		var data [][]string
-		for _, node := range resp.Payload.Nodes {
-			str := []string{node.ID, node.Architecture, node.Version, node.Distribution, node.RegionID, node.CPUCapacity, node.CPUAvailable,
-				node.MemoryCapacity, node.MemoryAvailable, node.DiskCapacity, node.DiskAvailable, node.Status}
-			data = append(data, str)
-		}
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "Architecture", "Version", "Distribution", "RegionID", "CPUCapacity", "CPUAvailable",
-			"MemoryCapacity", "MemoryAvailable", "DiskCapacity", "DiskAvailable", "Status"})
-		table.AppendBulk(data)
-		table.Render()
+	if !c.Bool("yes") && !confirm(question) {
+		return fmt.Errorf("Error: delete cancelled")
 	}
-	if resp, ok := any.(*operations.GetNodesIDOK); ok {
-		var data [][]string
-		node := resp.Payload
-		str := []string{node.ID, node.Architecture, node.Version, node.Distribution, node.RegionID, node.CPUCapacity, node.CPUAvailable,
-			node.MemoryCapacity, node.MemoryAvailable, node.DiskCapacity, node.DiskAvailable, node.Status}
-		data = append(data, str)
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "Architecture", "Version", "Distribution", "RegionID", "CPUCapacity", "CPUAvailable",
-			"MemoryCapacity", "MemoryAvailable", "DiskCapacity", "DiskAvailable", "Status"})
-		table.AppendBulk(data)
-		table.Render()
+	if _, err := cascade.Delete(ctx, client, trees, os.Stdout); err != nil {
+		return fmt.Errorf("Error: %s", err)
 	}
-	if resp, ok := any.(*operations.GetRegionsOK); ok {
-		var data [][]string
-		for _, reg := range resp.Payload.Regions {
-			relids := ""
-			for _, rel := range reg.Relationships {
-				if relids == "" {
-					relids = rel.RelationshipID
-				} else {
-					relids = relids + "," + rel.RelationshipID
-				}
-			}
-			var cpuPrice, memPrice, diskPrice string
-			if reg.Prices != nil {
-				cpuPrice = strconv.FormatFloat(reg.Prices.CPU.MinPrice, 'f', 2, 64) + "," +
-					strconv.FormatFloat(reg.Prices.CPU.MaxPrice, 'f', 2, 64) + "," +
-					strconv.FormatFloat(reg.Prices.CPU.Scarcity, 'f', 2, 64) + "," +
-					strconv.FormatFloat(reg.Prices.CPU.UnitPrice, 'f', 2, 64)
-				memPrice = strconv.FormatFloat(reg.Prices.Memory.MinPrice, 'f', 2, 64) + "," +
-					strconv.FormatFloat(reg.Prices.Memory.MaxPrice, 'f', 2, 64) + "," +
-					strconv.FormatFloat(reg.Prices.Memory.Scarcity, 'f', 2, 64) + "," +
-					strconv.FormatFloat(reg.Prices.Memory.UnitPrice, 'f', 2, 64)
-				diskPrice = strconv.FormatFloat(reg.Prices.Disk.MinPrice, 'f', 2, 64) + "," +
-					strconv.FormatFloat(reg.Prices.Disk.MaxPrice, 'f', 2, 64) + "," +
-					strconv.FormatFloat(reg.Prices.Disk.Scarcity, 'f', 2, 64) + "," +
-					strconv.FormatFloat(reg.Prices.Disk.UnitPrice, 'f', 2, 64)
-			}
-			str := []string{reg.ID, reg.Description, reg.Location, strconv.FormatInt(reg.Tier, 10), cpuPrice, memPrice, diskPrice, relids}
-			data = append(data, str)
-		}
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "Description", "Location", "Tier", "CPUPrice", "MemPrice", "DiskPrice", "Relationship Id"})
-		table.AppendBulk(data)
-		table.Render()
+	return nil
+}
+
+// confirm asks a question on stdin and reports whether the user typed yes.
+func confirm(question string) bool {
+	fmt.Printf("%s Type yes to confirm: ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
+
+func handlePutAll(c *cli.Context) error {
+	client := newClient(c)
+
+	conf, err := loadConfFromContext(c)
+	if err != nil {
+		return err
 	}
-	if resp, ok := any.(*operations.GetRegionsIDOK); ok {
-		var data [][]string
-		reg := resp.Payload
-		relids := ""
-		for _, rel := range reg.Relationships {
-			if relids == "" {
-				relids = rel.RelationshipID
-			} else {
-				relids = relids + "," + rel.RelationshipID
-			}
-		}
-		cpuPrice := strconv.FormatFloat(reg.Prices.CPU.MinPrice, 'f', 2, 64) + "," +
-			strconv.FormatFloat(reg.Prices.CPU.MaxPrice, 'f', 2, 64) + "," +
-			strconv.FormatFloat(reg.Prices.CPU.Scarcity, 'f', 2, 64) + "," +
-			strconv.FormatFloat(reg.Prices.CPU.UnitPrice, 'f', 2, 64)
-		memPrice := strconv.FormatFloat(reg.Prices.Memory.MinPrice, 'f', 2, 64) + "," +
-			strconv.FormatFloat(reg.Prices.Memory.MaxPrice, 'f', 2, 64) + "," +
-			strconv.FormatFloat(reg.Prices.Memory.Scarcity, 'f', 2, 64) + "," +
-			strconv.FormatFloat(reg.Prices.Memory.UnitPrice, 'f', 2, 64)
-		diskPrice := strconv.FormatFloat(reg.Prices.Disk.MinPrice, 'f', 2, 64) + "," +
-			strconv.FormatFloat(reg.Prices.Disk.MaxPrice, 'f', 2, 64) + "," +
-			strconv.FormatFloat(reg.Prices.Disk.Scarcity, 'f', 2, 64) + "," +
-			strconv.FormatFloat(reg.Prices.Disk.UnitPrice, 'f', 2, 64)
-		str := []string{reg.ID, reg.Description, reg.Location, strconv.FormatInt(reg.Tier, 10), cpuPrice, memPrice, diskPrice, relids}
-		data = append(data, str)
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "Description", "Location", "Tier", "CPUPrice", "MemPrice", "DiskPrice", "Relationship Id"})
-		table.AppendBulk(data)
-		table.Render()
+
+	_, err = apply.Apply(context.Background(), client, conf, apply.Options{
+		Atomic: c.Bool("atomic"),
+		Log:    os.Stdout,
+	})
+	if err != nil {
+		return fmt.Errorf("Error: putAll aborted: %s", err)
 	}
-	if resp, ok := any.(*operations.GetRelationshipsOK); ok {
-		var data [][]string
-		for _, rel := range resp.Payload.Relationships {
-			var bwPrice, latPrice string
-			if rel.Prices != nil {
-				bwPrice = strconv.FormatFloat(rel.Prices.Bandwidth.MinPrice, 'f', 2, 64) + "," +
-					strconv.FormatFloat(rel.Prices.Bandwidth.MaxPrice, 'f', 2, 64) + "," +
-					strconv.FormatFloat(rel.Prices.Bandwidth.Scarcity, 'f', 2, 64) + "," +
-					strconv.FormatFloat(rel.Prices.Bandwidth.UnitPrice, 'f', 2, 64)
-				latPrice = strconv.FormatFloat(rel.Prices.Latency.MinPrice, 'f', 2, 64) + "," +
-					strconv.FormatFloat(rel.Prices.Latency.MaxPrice, 'f', 2, 64) + "," +
-					strconv.FormatFloat(rel.Prices.Latency.Scarcity, 'f', 2, 64) + "," +
-					strconv.FormatFloat(rel.Prices.Latency.UnitPrice, 'f', 2, 64)
-			}
-			str := []string{rel.ID, rel.EndpointA, rel.EndpointB, rel.RegionID, strconv.FormatInt(rel.BandwidthCapacity, 10),
-				strconv.FormatInt(rel.BandwidthAvailable, 10), strconv.FormatInt(rel.Latency, 10), bwPrice, latPrice, rel.Status}
-			data = append(data, str)
-		}
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "EndpointA", "EndpointB", "RegionID", "BandwidthCapacity", "BandwidthAvailable",
-			"Latency", "BandwidthPrice", "LatencyPrice", "Status"})
-		table.AppendBulk(data)
-		table.Render()
+	return nil
+}
+
+func handleDeleteAll(c *cli.Context) error {
+	ctx := context.Background()
+	client := newClient(c)
+	rt, err := resource.Lookup(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("Error: %s", err)
 	}
-	if resp, ok := any.(*operations.GetRelationshipsIDOK); ok {
-		var data [][]string
-		rel := resp.Payload
-		bwPrice := strconv.FormatFloat(rel.Prices.Bandwidth.MinPrice, 'f', 2, 64) + "," +
-			strconv.FormatFloat(rel.Prices.Bandwidth.MaxPrice, 'f', 2, 64) + "," +
-			strconv.FormatFloat(rel.Prices.Bandwidth.Scarcity, 'f', 2, 64) + "," +
-			strconv.FormatFloat(rel.Prices.Bandwidth.UnitPrice, 'f', 2, 64)
-		latPrice := strconv.FormatFloat(rel.Prices.Latency.MinPrice, 'f', 2, 64) + "," +
-			strconv.FormatFloat(rel.Prices.Latency.MaxPrice, 'f', 2, 64) + "," +
-			strconv.FormatFloat(rel.Prices.Latency.Scarcity, 'f', 2, 64) + "," +
-			strconv.FormatFloat(rel.Prices.Latency.UnitPrice, 'f', 2, 64)
-		str := []string{rel.ID, rel.EndpointA, rel.EndpointB, rel.RegionID, strconv.FormatInt(rel.BandwidthCapacity, 10),
-			strconv.FormatInt(rel.BandwidthAvailable, 10), strconv.FormatInt(rel.Latency, 10), bwPrice, latPrice, rel.Status}
-		data = append(data, str)
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "EndpointA", "EndpointB", "RegionID", "BandwidthCapacity", "BandwidthAvailable",
-			"Latency", "BandwidthPrice", "LatencyPrice", "Status"})
-		table.AppendBulk(data)
-		table.Render()
+
+	objs, err := resource.List(ctx, client, rt.Name, nil)
+	if err != nil {
+		return fmt.Errorf("Error: get %s failed: %s", rt.Name, err)
 	}
-	if resp, ok := any.(*operations.GetExternalendpointsOK); ok {
-		var data [][]string
-		for _, th := range resp.Payload.Externalendpoints {
-			str := []string{th.ID, th.Name, th.Description, th.Type, th.Location, th.RegionID, th.IPAddress}
-			data = append(data, str)
-		}
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "Name", "Description", "Type", "Location", "RegionID", "IPAddress"})
-		table.AppendBulk(data)
-		table.Render()
+	var ids []string
+	for _, obj := range objs {
+		ids = append(ids, rt.ID(obj))
 	}
-	if resp, ok := any.(*operations.GetExternalendpointsIDOK); ok {
-		var data [][]string
-		th := resp.Payload
-		str := []string{th.ID, th.Name, th.Description, th.Type, th.Location, th.RegionID, th.IPAddress}
-		data = append(data, str)
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "Name", "Description", "Type", "Location", "RegionID", "IPAddress"})
-		table.AppendBulk(data)
-		table.Render()
+	if err := checkProtected(c, protectedTrees(rt.Name, ids)); err != nil {
+		return err
 	}
-	if resp, ok := any.(*operations.GetDynamicnodesOK); ok {
-		var data [][]string
-		for _, dyn := range resp.Payload.Dynamicnodes {
-			str := []string{dyn.ID, dyn.IPAddress, dyn.NodeID, dyn.RegionID}
-			data = append(data, str)
+	failed := 0
+	for _, obj := range objs {
+		id := rt.ID(obj)
+		msg, err := rt.Delete(ctx, client, id)
+		if err != nil {
+			fmt.Printf("Error: delete %s %s failed: %s\n", rt.Name, id, err)
+			failed++
+			continue
 		}
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "IPAddress", "NodeID", "RegionID"})
-		table.AppendBulk(data)
-		table.Render()
+		fmt.Printf("%s\n", msg)
 	}
-	if resp, ok := any.(*operations.GetDynamicnodesIDOK); ok {
-		var data [][]string
-		dyn := resp.Payload
-		str := []string{dyn.ID, dyn.IPAddress, dyn.NodeID, dyn.RegionID}
-		data = append(data, str)
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "IPAddress", "NodeID", "RegionID"})
-		table.AppendBulk(data)
-		table.Render()
+	if failed > 0 {
+		return fmt.Errorf("Error: %d of %d deletions failed", failed, len(objs))
 	}
-
+	return nil
 }
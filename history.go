@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fogatlas/fogatlasctl/pkg/audit"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli"
+)
+
+// parseSince accepts a duration back from now (e.g. 2h) or a time in RFC 3339
+// format.
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("Error: --since specified (%s) is neither a duration (e.g. 2h) nor a time (e.g. 2019-06-01T10:00:00Z)", s)
+	}
+	return t, nil
+}
+
+func handleHistory(c *cli.Context) error {
+	filter := audit.Filter{
+		User:    c.String("user"),
+		Context: c.String("context"),
+		Command: c.String("command"),
+		Failed:  c.Bool("failed"),
+	}
+	if c.Args().Present() {
+		rt, err := resource.Lookup(c.Args().First())
+		if err != nil {
+			return fmt.Errorf("Error: %s", err)
+		}
+		filter.Resource = rt.Name
+		filter.IDs = c.Args().Tail()
+	}
+	if c.String("since") != "" {
+		since, err := parseSince(c.String("since"))
+		if err != nil {
+			return err
+		}
+		filter.Since = since
+	}
+
+	entries, err := audit.Read(audit.Path())
+	if err != nil {
+		return fmt.Errorf("Error: unable to read the audit log: %s", err)
+	}
+	selected := []audit.Entry{}
+	for _, e := range entries {
+		if filter.Matches(e) {
+			selected = append(selected, e)
+		}
+	}
+	if limit := c.Int("limit"); limit > 0 && len(selected) > limit {
+		selected = selected[len(selected)-limit:]
+	}
+
+	if c.String("output") != "table" {
+		return printObject(map[string]interface{}{"entries": selected}, c.String("output"))
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Time", "User", "Context", "Command", "Operation", "Resource", "ID", "Status"})
+	for _, e := range selected {
+		status := strconv.Itoa(e.Status)
+		if e.Status == 0 {
+			status = e.Error
+		}
+		table.Append([]string{e.Time.Local().Format("2006-01-02 15:04:05"), e.User, e.Context, e.Command, e.Operation, e.Resource, e.ID, status})
+	}
+	table.Render()
+	return nil
+}
+
+func handleUndo(c *cli.Context) error {
+	if c.Int("steps") < 1 {
+		return fmt.Errorf("Error: --steps must be at least 1")
+	}
+	entries, err := audit.Read(audit.Path())
+	if err != nil {
+		return fmt.Errorf("Error: unable to read the audit log: %s", err)
+	}
+	steps, err := audit.Plan(entries, c.String("endpoint"), c.Int("steps"))
+	if err != nil {
+		return fmt.Errorf("Error: unable to undo: %s", err)
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("Error: nothing to undo on %s", c.String("endpoint"))
+	}
+	for _, s := range steps {
+		fmt.Printf("%s\n", s)
+	}
+	// undo deletes the resources it created and overwrites the ones it
+	// restores, audit.Undo refuses both on protected resources like delete
+	protection, err := protecting(c)
+	if err != nil {
+		return err
+	}
+	if !c.Bool("yes") && !confirm(fmt.Sprintf("Undo these %d changes?", len(steps))) {
+		return fmt.Errorf("Error: undo cancelled")
+	}
+	isProtected := func(res string, id string) bool { return protection(res, id) != nil }
+	if done, err := audit.Undo(context.Background(), newClient(c), steps, isProtected, os.Stdout); err != nil {
+		if done == 0 {
+			return fmt.Errorf("Error: unable to undo: %s", err)
+		}
+		return fmt.Errorf("Error: undo stopped, run it again to resume: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/fogatlas/fogatlasctl/pkg/api"
+	"github.com/fogatlas/fogatlasctl/pkg/manifest"
+	"github.com/fogatlas/fogatlasctl/pkg/mock"
+	"github.com/urfave/cli"
+)
+
+func handleMockServer(c *cli.Context) error {
+	if rate := c.Float64("error-rate"); rate < 0 || rate > 1 {
+		return fmt.Errorf("Error: --error-rate must be between 0 and 1")
+	}
+	server := mock.NewServer(mock.Options{
+		Latency:         c.Duration("latency"),
+		ErrorRate:       c.Float64("error-rate"),
+		ErrorStatus:     c.Int("error-status"),
+		TransitionDelay: c.Duration("transition-delay"),
+		Log:             os.Stdout,
+	})
+	if len(c.StringSlice("seed")) > 0 {
+		conf, err := manifest.Load(manifest.Options{Files: c.StringSlice("seed")})
+		if err != nil {
+			return fmt.Errorf("Error: %s", err)
+		}
+		if err := server.Seed(conf); err != nil {
+			return fmt.Errorf("Error: seed failed: %s", err)
+		}
+	}
+	fmt.Printf("FogAtlas mock API listening on %s%s\n", c.String("listen"), api.BasePath)
+	if err := http.ListenAndServe(c.String("listen"), server); err != nil {
+		return fmt.Errorf("Error: %s", err)
+	}
+	return nil
+}
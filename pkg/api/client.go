@@ -0,0 +1,29 @@
+// Package api creates the clients of the FogAtlas API used by fogatlasctl.
+package api
+
+import (
+	"net/http"
+
+	apiclient "github.com/fogatlas/client-go/client"
+	"github.com/fogatlas/client-go/client/operations"
+	httptransport "github.com/go-openapi/runtime/client"
+	"github.com/go-openapi/strfmt"
+)
+
+// DefaultEndpoint is the endpoint of an API running on the local host.
+const DefaultEndpoint = "127.0.0.1:8080"
+
+// BasePath is the path of the version of the API supported by fogatlasctl.
+const BasePath = "/api/v2.0.0"
+
+// NewClient returns a client of the API listening at endpoint (host:port).
+func NewClient(endpoint string) *operations.Client {
+	return NewClientWithHTTP(endpoint, nil)
+}
+
+// NewClientWithHTTP is like NewClient but sends the requests with httpClient,
+// e.g. to set a timeout. A nil httpClient uses the default one.
+func NewClientWithHTTP(endpoint string, httpClient *http.Client) *operations.Client {
+	transport := httptransport.NewWithClient(endpoint, BasePath, []string{"http"}, httpClient)
+	return apiclient.New(transport, strfmt.Default).Operations
+}
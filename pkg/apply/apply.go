@@ -0,0 +1,96 @@
+// Package apply creates or updates a set of resources through the FogAtlas
+// API, as done by putAll.
+package apply
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/fogatlas/client-go/client/operations"
+	"github.com/fogatlas/fogatlasctl/pkg/manifest"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/fogatlas/fogatlasctl/pkg/update"
+)
+
+// Options configure Apply.
+type Options struct {
+	// Atomic rolls back all the changes if one of the resources cannot be
+	// loaded; otherwise the failed resources are skipped
+	Atomic bool
+	// Log receives the messages of the API and the errors, nil discards them
+	Log io.Writer
+}
+
+// Apply puts the resources of conf in the order they are listed by Items. It
+// returns the number of resources that could not be loaded; in atomic mode
+// the first failure aborts the apply and is returned as an error, after the
+// rollback.
+//
+// All the resources are retrieved before the first put and again right before
+// their own put: a resource changed by someone else in between is not
+// overwritten and fails with an *update.ConflictError. Outside atomic mode a
+// resource that cannot be retrieved beforehand is put without this check.
+func Apply(ctx context.Context, client *operations.Client, conf *manifest.Conf, opts Options) (int, error) {
+	var tx *Transaction
+	if opts.Atomic {
+		tx = &Transaction{Client: client, Log: opts.Log}
+	}
+	logf := func(format string, args ...interface{}) {
+		if opts.Log != nil {
+			fmt.Fprintf(opts.Log, format, args...)
+		}
+	}
+
+	items := conf.Items()
+	snapshots := make([]Change, len(items))
+	unchecked := make([]bool, len(items))
+	for i, item := range items {
+		var err error
+		snapshots[i], err = snapshot(ctx, client, item.Resource, item.ID)
+		if err != nil {
+			if tx != nil {
+				return len(items), err
+			}
+			logf("%s, it will be put without checking for concurrent changes\n", err)
+			unchecked[i] = true
+		}
+	}
+
+	failed := 0
+	for i, item := range items {
+		var err error
+		if !unchecked[i] {
+			err = update.Verify(ctx, client, item.Resource, item.ID, snapshots[i].Previous)
+		}
+		var msg string
+		if err == nil {
+			msg, err = resource.Put(ctx, client, item.Resource, item.ID, item.Obj)
+			if err != nil {
+				err = fmt.Errorf("put %s %s failed (%s)", item.Resource, item.ID, err)
+			}
+		}
+		if err != nil {
+			if tx != nil {
+				return 1, abort(ctx, tx, err)
+			}
+			logf("error while sending request: %s\n", err)
+			failed++
+			continue
+		}
+		if tx != nil {
+			tx.Record(snapshots[i])
+		}
+		logf("%s\n", msg)
+	}
+	return failed, nil
+}
+
+// abort rolls back a transaction after err and describes the outcome.
+func abort(ctx context.Context, tx *Transaction, err error) error {
+	total := len(tx.Changes)
+	if failed := tx.Rollback(ctx); failed > 0 {
+		return fmt.Errorf("%s, %d of %d resources could not be rolled back", err, failed, total)
+	}
+	return fmt.Errorf("%s, %d resources rolled back", err, total)
+}
@@ -0,0 +1,81 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/fogatlas/client-go/client/operations"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+)
+
+// Change is the state of a resource before it was updated. Previous is nil
+// when the resource did not exist and was created.
+type Change struct {
+	Resource string
+	ID       string
+	Previous interface{}
+}
+
+// Transaction keeps track of the resources touched by an atomic apply, so
+// that they can be brought back to their prior state if one of the requests
+// fails.
+type Transaction struct {
+	Client  *operations.Client
+	Changes []Change
+	// Log receives a line for every resource rolled back, nil discards them
+	Log io.Writer
+}
+
+// Snapshot retrieves the current state of a resource before it is updated.
+func (t *Transaction) Snapshot(ctx context.Context, res string, id string) (Change, error) {
+	return snapshot(ctx, t.Client, res, id)
+}
+
+func snapshot(ctx context.Context, client *operations.Client, res string, id string) (Change, error) {
+	prev, err := resource.Get(ctx, client, res, id)
+	if err != nil {
+		if !resource.IsNotFound(err) {
+			return Change{}, fmt.Errorf("unable to retrieve %s %s before update: %s", res, id, err)
+		}
+		prev = nil
+	}
+	return Change{Resource: res, ID: id, Previous: prev}, nil
+}
+
+// Record adds a change to the transaction once the update has been accepted.
+func (t *Transaction) Record(ch Change) {
+	t.Changes = append(t.Changes, ch)
+}
+
+// Rollback restores updated resources and deletes the newly created ones, in
+// reverse order. It returns the number of resources that could not be restored.
+func (t *Transaction) Rollback(ctx context.Context) int {
+	failed := 0
+	for i := len(t.Changes) - 1; i >= 0; i-- {
+		ch := t.Changes[i]
+		if ch.Previous == nil {
+			if _, err := resource.Delete(ctx, t.Client, ch.Resource, ch.ID); err != nil {
+				t.logf("rollback: unable to delete %s %s: %s\n", ch.Resource, ch.ID, err)
+				failed++
+				continue
+			}
+			t.logf("rollback: deleted %s %s\n", ch.Resource, ch.ID)
+		} else {
+			if _, err := resource.Put(ctx, t.Client, ch.Resource, ch.ID, ch.Previous); err != nil {
+				t.logf("rollback: unable to restore %s %s: %s\n", ch.Resource, ch.ID, err)
+				failed++
+				continue
+			}
+			t.logf("rollback: restored %s %s\n", ch.Resource, ch.ID)
+		}
+	}
+	t.Changes = nil
+	return failed
+}
+
+func (t *Transaction) logf(format string, args ...interface{}) {
+	if t.Log != nil {
+		fmt.Fprintf(t.Log, format, args...)
+	}
+}
@@ -0,0 +1,158 @@
+// Package audit records the requests changing the resources of the API in an
+// append-only log of json lines, so that the changes made with fogatlasctl
+// can be reconstructed.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fogatlas/fogatlasctl/pkg/config"
+)
+
+// PathEnv is the environment variable overriding the path of the log.
+const PathEnv = "FOGATLASCTL_AUDIT_LOG"
+
+// Entry is a request recorded in the log.
+type Entry struct {
+	Time time.Time `json:"time"`
+	User string    `json:"user"`
+	// Context is the API endpoint the request was sent to.
+	Context string `json:"context"`
+	// Command is the fogatlasctl command that sent the request, e.g. putAll,
+	// and Run identifies its invocation, shared by all its requests.
+	Command string `json:"command"`
+	Run     string `json:"run"`
+	// Operation is the HTTP method of the request.
+	Operation string `json:"operation"`
+	Resource  string `json:"resource"`
+	ID        string `json:"id,omitempty"`
+	// BodyHash is the sha256 of the body of the request, if any.
+	BodyHash string `json:"body_sha256,omitempty"`
+	// Status is the HTTP status of the response, 0 when none was received,
+	// in which case Error tells why.
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Before is the resource as retrieved right before the request, Absent
+	// tells that it did not exist. When both are empty the previous state is
+	// unknown.
+	Before json.RawMessage `json:"before,omitempty"`
+	Absent bool            `json:"absent,omitempty"`
+	// Undoes is the run undone by the request, if any.
+	Undoes string `json:"undoes,omitempty"`
+}
+
+// Succeeded reports whether the request changed the resource.
+func (e Entry) Succeeded() bool {
+	return e.Status >= 200 && e.Status < 300
+}
+
+// Path returns the path of the log: $FOGATLASCTL_AUDIT_LOG or audit.jsonl
+// next to the configuration file.
+func Path() string {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(config.Path()), "audit.jsonl")
+}
+
+// CurrentUser returns the name of the user running fogatlasctl.
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// NewRun returns an identifier for an invocation of fogatlasctl.
+func NewRun() string {
+	return fmt.Sprintf("%d-%d", time.Now().UnixNano(), os.Getpid())
+}
+
+// Append adds an entry at the end of the log at path, creating it.
+func Append(path string, e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns the entries of the log at path, oldest first. A missing log
+// has no entries.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Filter selects entries; its empty fields select everything.
+type Filter struct {
+	User     string
+	Context  string
+	Command  string
+	Resource string
+	IDs      []string
+	Since    time.Time
+	// Failed selects the requests that got no response or an error status.
+	Failed bool
+}
+
+// Matches reports whether the filter selects e.
+func (f Filter) Matches(e Entry) bool {
+	if f.User != "" && e.User != f.User ||
+		f.Context != "" && e.Context != f.Context ||
+		f.Command != "" && e.Command != f.Command ||
+		f.Resource != "" && e.Resource != f.Resource ||
+		!f.Since.IsZero() && e.Time.Before(f.Since) ||
+		f.Failed && e.Succeeded() {
+		return false
+	}
+	if len(f.IDs) == 0 {
+		return true
+	}
+	for _, id := range f.IDs {
+		if e.ID == id {
+			return true
+		}
+	}
+	return false
+}
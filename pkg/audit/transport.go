@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fogatlas/fogatlasctl/pkg/api"
+)
+
+// Transport is an http.RoundTripper recording in the log the requests that
+// change resources (PUT, PATCH, POST and DELETE), with the state of the
+// resource retrieved right before. The other requests are sent without being
+// recorded.
+type Transport struct {
+	// Base sends the requests, http.DefaultTransport if nil.
+	Base    http.RoundTripper
+	Path    string
+	User    string
+	Context string
+	Command string
+	Run     string
+	// Errors receives the failures to write the log, which do not fail the
+	// requests. Nil discards them.
+	Errors io.Writer
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// RoundTrip sends the request and records it.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete:
+	default:
+		return t.base().RoundTrip(req)
+	}
+
+	e := Entry{
+		Time:      time.Now().UTC(),
+		User:      t.User,
+		Context:   t.Context,
+		Command:   t.Command,
+		Run:       t.Run,
+		Operation: req.Method,
+	}
+	e.Resource, e.ID = SplitPath(req.URL.Path)
+	e.Undoes, _ = req.Context().Value(undoKey{}).(string)
+	if e.ID != "" && req.Method != http.MethodPost {
+		e.Before, e.Absent = t.before(req)
+	}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > 0 {
+			sum := sha256.Sum256(body)
+			e.BodyHash = hex.EncodeToString(sum[:])
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		e.Status = resp.StatusCode
+	}
+	if err := Append(t.Path, e); err != nil && t.Errors != nil {
+		fmt.Fprintf(t.Errors, "Warning: unable to write the audit log: %s\n", err)
+	}
+	return resp, err
+}
+
+// before retrieves the resource changed by req, returning it or whether it
+// does not exist. Both are empty when it cannot be retrieved.
+func (t *Transport) before(req *http.Request) (json.RawMessage, bool) {
+	get, err := http.NewRequest(http.MethodGet, req.URL.String(), nil)
+	if err != nil {
+		return nil, false
+	}
+	get = get.WithContext(req.Context())
+	get.Header.Set("Accept", "application/json")
+	resp, err := t.base().RoundTrip(get)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, true
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK || !json.Valid(body) {
+		return nil, false
+	}
+	return json.RawMessage(body), false
+}
+
+type undoKey struct{}
+
+// WithUndo returns a context whose requests are recorded as undoing run.
+func WithUndo(ctx context.Context, run string) context.Context {
+	return context.WithValue(ctx, undoKey{}, run)
+}
+
+// SplitPath returns the resource type and the identifier of an API path,
+// e.g. regions and EDGEA for /api/v2.0.0/regions/EDGEA.
+func SplitPath(path string) (string, string) {
+	path = strings.TrimPrefix(path, api.BasePath)
+	parts := strings.SplitN(strings.Trim(path, "/"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/fogatlas/client-go/client/operations"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+)
+
+// Step restores a resource to its state before a request: Before is put back,
+// or the resource is deleted when it was Absent.
+type Step struct {
+	Entry
+}
+
+func (s Step) String() string {
+	if s.Absent {
+		return fmt.Sprintf("delete %s %s (created by %s on %s)", s.Resource, s.ID, s.Command, s.Time.Local().Format("2006-01-02 15:04:05"))
+	}
+	return fmt.Sprintf("restore %s %s (changed by %s on %s)", s.Resource, s.ID, s.Command, s.Time.Local().Format("2006-01-02 15:04:05"))
+}
+
+// Plan returns the steps undoing the last n runs of fogatlasctl that changed
+// the resources of context, most recent change first. The runs undoing other
+// runs and the changes already undone are skipped, so that an undo stopped by
+// a failure can be resumed. It fails when a change cannot be undone because
+// the state of the resource before it is unknown.
+func Plan(entries []Entry, context string, n int) ([]Step, error) {
+	undone := map[string]bool{}
+	for _, e := range entries {
+		if e.Undoes != "" && (e.Succeeded() || e.Operation == http.MethodDelete && e.Status == http.StatusNotFound) {
+			undone[e.Undoes+"/"+e.Resource+"/"+e.ID] = true
+		}
+	}
+
+	var steps []Step
+	runs := map[string]bool{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Context != context || e.Undoes != "" || undone[e.Run+"/"+e.Resource+"/"+e.ID] || !e.Succeeded() || e.ID == "" {
+			continue
+		}
+		if !runs[e.Run] {
+			if len(runs) == n {
+				break
+			}
+			runs[e.Run] = true
+		}
+		if e.Before == nil && !e.Absent {
+			return nil, fmt.Errorf("the state of %s %s before %s on %s is unknown", e.Resource, e.ID, e.Command, e.Time.Local().Format("2006-01-02 15:04:05"))
+		}
+		steps = append(steps, Step{e})
+	}
+	return steps, nil
+}
+
+// Undo runs the steps in order, writing the messages of the API to log (nil
+// discards them). The requests sent are recorded as undoing the runs of the
+// steps when client records them with Transport. Nothing is done if
+// protected (nil protects nothing) reports a resource of the steps as
+// protected. It stops at the first failure and returns the number of steps
+// done.
+func Undo(ctx context.Context, client *operations.Client, steps []Step, protected func(res string, id string) bool, log io.Writer) (int, error) {
+	if protected != nil {
+		for _, s := range steps {
+			if protected(s.Resource, s.ID) {
+				return 0, fmt.Errorf("%s refused: %s %s is protected", s, s.Resource, s.ID)
+			}
+		}
+	}
+	for i, s := range steps {
+		stepCtx := WithUndo(ctx, s.Run)
+		var msg string
+		var err error
+		if s.Absent {
+			msg, err = resource.Delete(stepCtx, client, s.Resource, s.ID)
+		} else {
+			obj := resource.NewModel(s.Resource)
+			if obj == nil {
+				return i, fmt.Errorf("resource specified (%s) is unknown", s.Resource)
+			}
+			if err := json.Unmarshal(s.Before, obj); err != nil {
+				return i, fmt.Errorf("%s failed: %s", s, err)
+			}
+			msg, err = resource.Put(stepCtx, client, s.Resource, s.ID, obj)
+		}
+		if err != nil && s.Absent && resource.IsNotFound(err) {
+			// already deleted since
+			err = nil
+		}
+		if err != nil {
+			return i, fmt.Errorf("%s failed: %s", s, err)
+		}
+		if log != nil && msg != "" {
+			fmt.Fprintf(log, "%s\n", msg)
+		}
+	}
+	return len(steps), nil
+}
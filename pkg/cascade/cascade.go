@@ -0,0 +1,220 @@
+// Package cascade finds the resources that depend on other resources, e.g.
+// the nodes of a region, so that they can be deleted together.
+package cascade
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/fogatlas/client-go/client/operations"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+)
+
+// Tree is a resource and the resources depending on it.
+type Tree struct {
+	Resource string  `json:"resource"`
+	ID       string  `json:"id"`
+	Children []*Tree `json:"dependents,omitempty"`
+}
+
+// rule describes the dependents of a type: the resources of type child
+// whose fields hold the identifier of the parent, or whose identifier is
+// listed in parentField of the parent.
+type rule struct {
+	parent      string
+	child       string
+	fields      []string
+	parentField string
+}
+
+// rules are the dependencies between the resource types. The order matters:
+// the microservices of a region are found first through its nodes.
+var rules = []rule{
+	{parent: "regions", child: "nodes", fields: []string{"region_id"}},
+	{parent: "regions", child: "relationships", fields: []string{"region_id", "endpoint_a", "endpoint_b"}},
+	{parent: "regions", child: "externalendpoints", fields: []string{"region_id"}},
+	{parent: "regions", child: "dynamicnodes", fields: []string{"region_id"}},
+	{parent: "regions", child: "microservices", fields: []string{"region_id"}},
+	{parent: "nodes", child: "microservices", fields: []string{"node_id"}},
+	{parent: "nodes", child: "dynamicnodes", fields: []string{"node_id"}},
+	{parent: "applications", child: "microservices", fields: []string{"application_id"}, parentField: "microservices.microservice_id"},
+}
+
+// finder retrieves the resources of each type once.
+type finder struct {
+	ctx    context.Context
+	client *operations.Client
+	objs   map[string][]interface{}
+	seen   map[string]bool
+}
+
+func (f *finder) list(res string) ([]interface{}, error) {
+	if objs, ok := f.objs[res]; ok {
+		return objs, nil
+	}
+	objs, err := resource.List(f.ctx, f.client, res, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get %s failed: %s", res, err)
+	}
+	f.objs[res] = objs
+	return objs, nil
+}
+
+// find returns the resource of the given type and id, nil if it does not exist.
+func (f *finder) find(res string, id string) (interface{}, error) {
+	rt, err := resource.Lookup(res)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := f.list(rt.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		if rt.ID(obj) == id {
+			return obj, nil
+		}
+	}
+	return nil, nil
+}
+
+// tree builds the tree of a resource, skipping the dependents already found.
+func (f *finder) tree(res string, id string, obj interface{}) (*Tree, error) {
+	t := &Tree{Resource: res, ID: id}
+	for _, r := range rules {
+		if r.parent != res {
+			continue
+		}
+		listed := make(map[string]bool)
+		if r.parentField != "" {
+			for _, childID := range resource.Field(obj, r.parentField) {
+				listed[childID] = true
+			}
+		}
+		childType, err := resource.Lookup(r.child)
+		if err != nil {
+			return nil, err
+		}
+		children, err := f.list(childType.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			childID := childType.ID(child)
+			if f.seen[r.child+"/"+childID] {
+				continue
+			}
+			depends := listed[childID]
+			for _, field := range r.fields {
+				for _, value := range resource.Field(child, field) {
+					depends = depends || value == id
+				}
+			}
+			if !depends {
+				continue
+			}
+			f.seen[r.child+"/"+childID] = true
+			sub, err := f.tree(r.child, childID, child)
+			if err != nil {
+				return nil, err
+			}
+			t.Children = append(t.Children, sub)
+		}
+	}
+	return t, nil
+}
+
+// Dependents returns the trees of the resources of the given type and
+// identifiers with all the resources depending on them. A dependent appears
+// only once, under the first resource it depends on.
+func Dependents(ctx context.Context, client *operations.Client, res string, ids []string) ([]*Tree, error) {
+	rt, err := resource.Lookup(res)
+	if err != nil {
+		return nil, err
+	}
+	f := &finder{ctx: ctx, client: client, objs: make(map[string][]interface{}), seen: make(map[string]bool)}
+	for _, id := range ids {
+		f.seen[rt.Name+"/"+id] = true
+	}
+	var trees []*Tree
+	for _, id := range ids {
+		obj, err := f.find(rt.Name, id)
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			return nil, fmt.Errorf("%s %s does not exist", rt.Name, id)
+		}
+		t, err := f.tree(rt.Name, id, obj)
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, t)
+	}
+	return trees, nil
+}
+
+// Count returns the number of resources in the tree.
+func (t *Tree) Count() int {
+	n := 1
+	for _, child := range t.Children {
+		n += child.Count()
+	}
+	return n
+}
+
+// Print writes the trees with one resource per line:
+//
+//	regions/EDGEA
+//	├── nodes/node12
+//	│   └── microservices/ms1
+//	└── relationships/rel1
+func Print(w io.Writer, trees []*Tree) {
+	for _, t := range trees {
+		fmt.Fprintf(w, "%s/%s\n", t.Resource, t.ID)
+		printChildren(w, t.Children, "")
+	}
+}
+
+func printChildren(w io.Writer, children []*Tree, indent string) {
+	for i, child := range children {
+		branch, next := "├── ", "│   "
+		if i == len(children)-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s/%s\n", indent, branch, child.Resource, child.ID)
+		printChildren(w, child.Children, indent+next)
+	}
+}
+
+// Delete deletes the resources of the trees bottom-up, dependents before the
+// resources they depend on, writing the messages of the API to log (nil
+// discards them). It stops at the first failure, so that no resource is
+// deleted before its dependents, and returns the number of deleted resources.
+func Delete(ctx context.Context, client *operations.Client, trees []*Tree, log io.Writer) (int, error) {
+	deleted := 0
+	var walk func(t *Tree) error
+	walk = func(t *Tree) error {
+		for _, child := range t.Children {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		msg, err := resource.Delete(ctx, client, t.Resource, t.ID)
+		if err != nil {
+			return fmt.Errorf("delete %s %s failed: %s", t.Resource, t.ID, err)
+		}
+		deleted++
+		if log != nil {
+			fmt.Fprintf(log, "%s\n", msg)
+		}
+		return nil
+	}
+	for _, t := range trees {
+		if err := walk(t); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,64 @@
+// Package config reads and writes the configuration file of fogatlasctl,
+// which holds the settings kept between two invocations, e.g. the resources
+// protected from deletion.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+)
+
+// PathEnv is the environment variable overriding the path of the
+// configuration file.
+const PathEnv = "FOGATLASCTL_CONFIG"
+
+// Config is the content of the configuration file.
+type Config struct {
+	Protected []Protection `json:"protected,omitempty"`
+}
+
+// Path returns the path of the configuration file: $FOGATLASCTL_CONFIG or
+// config.yaml in the fogatlasctl directory of the user configuration.
+func Path() string {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "fogatlasctl", "config.yaml")
+}
+
+// Load reads the configuration file at path. A missing file is an empty
+// configuration.
+func Load(path string) (*Config, error) {
+	conf := &Config{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return conf, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, conf); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return conf, nil
+}
+
+// Save writes the configuration to path, creating its directory.
+func (conf *Config) Save(path string) error {
+	data, err := yaml.Marshal(conf)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
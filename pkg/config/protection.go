@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// Protection protects the resources matching it from deletion. Each field is
+// a glob pattern (see path.Match); an empty field matches everything. The
+// context is the API endpoint the command is sent to.
+type Protection struct {
+	Context  string `json:"context,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	ID       string `json:"id,omitempty"`
+}
+
+func (p Protection) String() string {
+	orAny := func(s string) string {
+		if s == "" {
+			return "*"
+		}
+		return s
+	}
+	return fmt.Sprintf("%s %s/%s", orAny(p.Context), orAny(p.Resource), orAny(p.ID))
+}
+
+// Matches reports whether the resource res/id of context is protected.
+func (p Protection) Matches(context string, res string, id string) bool {
+	return match(p.Context, context) && match(p.Resource, res) && match(p.ID, id)
+}
+
+func match(pattern string, s string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, err := path.Match(pattern, s)
+	return err == nil && ok
+}
+
+// Check validates the patterns of the protection.
+func (p Protection) Check() error {
+	for _, pattern := range []string{p.Context, p.Resource, p.ID} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("pattern specified (%s) is not valid", pattern)
+		}
+	}
+	return nil
+}
+
+// Protecting returns the first protection matching the resource res/id of
+// context, nil if the resource is not protected.
+func (conf *Config) Protecting(context string, res string, id string) *Protection {
+	for i, p := range conf.Protected {
+		if p.Matches(context, res, id) {
+			return &conf.Protected[i]
+		}
+	}
+	return nil
+}
+
+// Protect adds a protection and reports whether it was not already present.
+func (conf *Config) Protect(p Protection) bool {
+	for _, q := range conf.Protected {
+		if q == p {
+			return false
+		}
+	}
+	conf.Protected = append(conf.Protected, p)
+	return true
+}
+
+// Unprotect removes a protection, given with the same patterns it was added
+// with, and reports whether it was present.
+func (conf *Config) Unprotect(p Protection) bool {
+	for i, q := range conf.Protected {
+		if q == p {
+			conf.Protected = append(conf.Protected[:i], conf.Protected[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
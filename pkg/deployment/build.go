@@ -0,0 +1,96 @@
+// Package deployment builds and checks FogAtlas deployments from Kubernetes
+// manifests.
+package deployment
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fogatlas/client-go/models"
+	"github.com/fogatlas/fogatlasctl/pkg/manifest"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/ghodss/yaml"
+)
+
+// BuildOptions are the fields of the deployment created by Build.
+type BuildOptions struct {
+	Name        string
+	Description string
+	// ExternalEndpointID is the external endpoint the first dataflow starts from
+	ExternalEndpointID string
+	// BandwidthRequired and LatencyRequired are the requirements of the
+	// placeholder dataflows
+	BandwidthRequired int64
+	LatencyRequired   int64
+}
+
+// Build creates the skeleton of a FogAtlas deployment from a set of Kubernetes
+// manifests, read from files, directories or glob patterns. Every Kubernetes
+// Deployment becomes a microservice whose requirements are the resource
+// requests of its containers; the other objects are ignored. The dataflows
+// chain the external endpoint and the microservices in the order they are
+// read, and are meant to be edited.
+func Build(inputs []string, opts BuildOptions) (*models.Deployment, error) {
+	files, err := manifest.ExpandInputs(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	depl := &models.Deployment{
+		Name:               opts.Name,
+		Description:        opts.Description,
+		ExternalendpointID: opts.ExternalEndpointID,
+		Status:             "todeploy",
+	}
+	for _, filename := range files {
+		data, err := manifest.ReadInput(filename)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read file %s: %s", filename, err)
+		}
+		for _, doc := range manifest.SplitDocuments(data) {
+			var obj k8sDeployment
+			if err := yaml.Unmarshal(doc, &obj); err != nil {
+				return nil, fmt.Errorf("wrong file format in %s: %s", filename, err)
+			}
+			if obj.Kind != "Deployment" {
+				continue
+			}
+			cpu, memory, disk, err := obj.requests()
+			if err != nil {
+				return nil, fmt.Errorf("wrong resource requests in deployment %s: %s", obj.Metadata.Name, err)
+			}
+			depl.Microservices = append(depl.Microservices, &models.DeploymentMicroservice{
+				Name:                 obj.Metadata.Name,
+				CPURequired:          resource.FormatCPU(cpu),
+				MemoryRequired:       resource.FormatMebibytes(memory),
+				DiskRequired:         resource.FormatMebibytes(disk),
+				DeploymentDescriptor: string(doc),
+			})
+		}
+	}
+	if len(depl.Microservices) == 0 {
+		return nil, fmt.Errorf("no Kubernetes Deployment found")
+	}
+
+	// placeholder dataflows, unmarshalled so that they have the same type as
+	// the ones of a deployment read from a file
+	var dataflows []map[string]interface{}
+	source := depl.ExternalendpointID
+	for _, ms := range depl.Microservices {
+		dataflows = append(dataflows, map[string]interface{}{
+			"source_id":          source,
+			"destination_id":     ms.Name,
+			"bandwidth_required": opts.BandwidthRequired,
+			"latency_required":   opts.LatencyRequired,
+		})
+		source = ms.Name
+	}
+	b, err := json.Marshal(dataflows)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &depl.Dataflows); err != nil {
+		return nil, err
+	}
+	return depl, nil
+}
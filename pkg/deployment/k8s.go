@@ -0,0 +1,79 @@
+package deployment
+
+import (
+	"fmt"
+
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+)
+
+// k8sObject is the subset of a Kubernetes object used to recognize its type.
+type k8sObject struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+// k8sDeployment is the subset of a Kubernetes Deployment used by fogatlasctl.
+type k8sDeployment struct {
+	k8sObject
+	Spec struct {
+		Replicas *int64                 `json:"replicas"`
+		Selector map[string]interface{} `json:"selector"`
+		Template struct {
+			Spec struct {
+				Containers []k8sContainer `json:"containers"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+}
+
+// k8sContainer is the subset of a Kubernetes container used by fogatlasctl.
+type k8sContainer struct {
+	Name      string `json:"name"`
+	Resources struct {
+		Requests map[string]interface{} `json:"requests"`
+	} `json:"resources"`
+}
+
+// replicas returns the number of replicas of the deployment (1 if not set).
+func (d *k8sDeployment) replicas() int64 {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
+
+// requests returns the sum of the resource requests of all the replicas of
+// the deployment, in millicores for cpu and in bytes for memory and disk.
+func (d *k8sDeployment) requests() (cpu int64, memory int64, disk int64, err error) {
+	for _, container := range d.Spec.Template.Spec.Containers {
+		for name, value := range container.Resources.Requests {
+			quantity := fmt.Sprint(value)
+			switch name {
+			case "cpu":
+				v, err := resource.ParseCPU(quantity)
+				if err != nil {
+					return 0, 0, 0, fmt.Errorf("container %s: %s", container.Name, err)
+				}
+				cpu += v
+			case "memory":
+				v, err := resource.ParseBytes(quantity)
+				if err != nil {
+					return 0, 0, 0, fmt.Errorf("container %s: %s", container.Name, err)
+				}
+				memory += v
+			case "ephemeral-storage":
+				v, err := resource.ParseBytes(quantity)
+				if err != nil {
+					return 0, 0, 0, fmt.Errorf("container %s: %s", container.Name, err)
+				}
+				disk += v
+			}
+		}
+	}
+	n := d.replicas()
+	return cpu * n, memory * n, disk * n, nil
+}
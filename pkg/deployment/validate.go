@@ -0,0 +1,102 @@
+package deployment
+
+import (
+	"fmt"
+
+	"github.com/fogatlas/client-go/models"
+	"github.com/fogatlas/fogatlasctl/pkg/manifest"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/ghodss/yaml"
+)
+
+// deprecatedDeploymentAPIs are the apiVersions of Deployment removed in Kubernetes 1.16.
+var deprecatedDeploymentAPIs = map[string]bool{
+	"extensions/v1beta1": true,
+	"apps/v1beta1":       true,
+	"apps/v1beta2":       true,
+}
+
+// ValidateDescriptor checks the deployment descriptor of a microservice: it
+// must be a single Kubernetes Deployment whose resource requests fit in the
+// cpu and memory required to FogAtlas. It returns the warnings and the errors found.
+func ValidateDescriptor(descriptor string, cpuRequired string, memoryRequired string) (warnings []string, errs []string) {
+	if descriptor == "" {
+		return nil, []string{"deployment_descriptor is empty"}
+	}
+	docs := manifest.SplitDocuments([]byte(descriptor))
+	if len(docs) != 1 {
+		return nil, []string{fmt.Sprintf("deployment_descriptor must contain a single object, found %d", len(docs))}
+	}
+	var obj k8sDeployment
+	if err := yaml.Unmarshal(docs[0], &obj); err != nil {
+		return nil, []string{fmt.Sprintf("deployment_descriptor is not valid yaml: %s", err)}
+	}
+
+	if obj.Kind != "Deployment" {
+		errs = append(errs, fmt.Sprintf("kind %q is not supported, expected Deployment", obj.Kind))
+	}
+	switch {
+	case obj.APIVersion == "apps/v1":
+		if obj.Spec.Selector == nil {
+			errs = append(errs, "spec.selector is required by apps/v1")
+		}
+	case deprecatedDeploymentAPIs[obj.APIVersion]:
+		warnings = append(warnings, fmt.Sprintf("apiVersion %s is deprecated and not served since Kubernetes 1.16, use apps/v1", obj.APIVersion))
+	default:
+		errs = append(errs, fmt.Sprintf("apiVersion %q is not supported, expected apps/v1", obj.APIVersion))
+	}
+	if len(obj.Spec.Template.Spec.Containers) == 0 {
+		errs = append(errs, "spec.template.spec.containers is empty")
+		return warnings, errs
+	}
+
+	cpu, memory, _, err := obj.requests()
+	if err != nil {
+		return warnings, append(errs, err.Error())
+	}
+	if cpu == 0 {
+		warnings = append(warnings, "containers have no cpu request")
+	} else if cpuRequired != "" {
+		required, err := resource.ParseCPU(cpuRequired)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("cpu_required: %s", err))
+		} else if cpu > required {
+			errs = append(errs, fmt.Sprintf("cpu requests (%s) exceed cpu_required (%s)", resource.FormatCPU(cpu), cpuRequired))
+		}
+	}
+	if memory == 0 {
+		warnings = append(warnings, "containers have no memory request")
+	} else if memoryRequired != "" {
+		required, err := resource.ParseBytes(memoryRequired)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("memory_required: %s", err))
+		} else if memory > required {
+			errs = append(errs, fmt.Sprintf("memory requests (%s) exceed memory_required (%s)", resource.FormatMebibytes(memory), memoryRequired))
+		}
+	}
+	return warnings, errs
+}
+
+// Problem is an issue found in the descriptor of a microservice.
+type Problem struct {
+	Microservice string
+	// Warning is set for the issues that do not make the deployment invalid
+	Warning bool
+	Message string
+}
+
+// Validate checks the descriptors of all the microservices of a deployment
+// and returns the problems found.
+func Validate(depl *models.Deployment) []Problem {
+	var problems []Problem
+	for _, ms := range depl.Microservices {
+		warnings, errs := ValidateDescriptor(ms.DeploymentDescriptor, ms.CPURequired, ms.MemoryRequired)
+		for _, w := range warnings {
+			problems = append(problems, Problem{Microservice: ms.Name, Warning: true, Message: w})
+		}
+		for _, e := range errs {
+			problems = append(problems, Problem{Microservice: ms.Name, Message: e})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,354 @@
+// Package describe joins a resource of the FogAtlas API with the resources
+// related to it, e.g. a region with its nodes and the microservices running
+// on them.
+package describe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/fogatlas/client-go/client/operations"
+	"github.com/fogatlas/client-go/models"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+)
+
+// Capacity is the sum of the capacity of a set of nodes.
+type Capacity struct {
+	Nodes           int    `json:"nodes"`
+	CPUCapacity     string `json:"cpu_capacity"`
+	CPUAvailable    string `json:"cpu_available"`
+	MemoryCapacity  string `json:"memory_capacity"`
+	MemoryAvailable string `json:"memory_available"`
+	DiskCapacity    string `json:"disk_capacity"`
+	DiskAvailable   string `json:"disk_available"`
+	// Warnings lists the quantities that could not be added
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// RegionView is a region with the resources it contains.
+type RegionView struct {
+	Region            *models.Region             `json:"region"`
+	Capacity          Capacity                   `json:"capacity"`
+	Nodes             []*models.Node             `json:"nodes"`
+	Microservices     []*models.Microservice     `json:"microservices"`
+	Relationships     []*models.Relationship     `json:"relationships"`
+	ExternalEndpoints []*models.ExternalEndpoint `json:"externalendpoints"`
+	DynamicNodes      []*models.DynamicNode      `json:"dynamicnodes"`
+}
+
+// NodeView is a node with its region and the resources running on it.
+type NodeView struct {
+	Node          *models.Node           `json:"node"`
+	Region        *models.Region         `json:"region,omitempty"`
+	Capacity      Capacity               `json:"capacity"`
+	Microservices []*models.Microservice `json:"microservices"`
+	DynamicNodes  []*models.DynamicNode  `json:"dynamicnodes"`
+}
+
+// DeploymentView is a deployment with the regions of its microservices and
+// the sum of their requirements.
+type DeploymentView struct {
+	Deployment       *models.Deployment       `json:"deployment"`
+	ExternalEndpoint *models.ExternalEndpoint `json:"externalendpoint,omitempty"`
+	Regions          []*models.Region         `json:"regions"`
+	Requirements     Requirements             `json:"requirements"`
+}
+
+// Requirements is the sum of the requirements of the microservices of a deployment.
+type Requirements struct {
+	Microservices  int     `json:"microservices"`
+	CPURequired    string  `json:"cpu_required"`
+	MemoryRequired string  `json:"memory_required"`
+	DiskRequired   string  `json:"disk_required"`
+	PriceRequired  float64 `json:"price_required"`
+	PriceComputed  float64 `json:"price_computed"`
+	// Warnings lists the quantities that could not be added
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ApplicationView is an application with its microservices and the nodes
+// and regions they run on.
+type ApplicationView struct {
+	Application   *models.Application    `json:"application"`
+	Microservices []*models.Microservice `json:"microservices"`
+	Nodes         []*models.Node         `json:"nodes"`
+	Regions       []*models.Region       `json:"regions"`
+}
+
+// Region retrieves a region and joins its nodes, the microservices running on
+// them, the relationships touching it, its external endpoints and dynamic
+// nodes.
+func Region(ctx context.Context, client *operations.Client, id string) (*RegionView, error) {
+	obj, err := resource.Get(ctx, client, "regions", id)
+	if err != nil {
+		return nil, fmt.Errorf("get regions %s failed: %s", id, err)
+	}
+	view := &RegionView{Region: obj.(*models.Region)}
+	inRegion := map[string]string{"region_id": id}
+
+	nodes, err := list(ctx, client, "nodes", inRegion)
+	if err != nil {
+		return nil, err
+	}
+	onNodes := make(map[string]bool)
+	for _, obj := range nodes {
+		node := obj.(*models.Node)
+		view.Nodes = append(view.Nodes, node)
+		onNodes[node.ID] = true
+	}
+	view.Capacity = capacity(view.Nodes)
+
+	microservices, err := list(ctx, client, "microservices", nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range microservices {
+		if ms := obj.(*models.Microservice); onNodes[ms.NodeID] || ms.RegionID == id {
+			view.Microservices = append(view.Microservices, ms)
+		}
+	}
+
+	relationships, err := list(ctx, client, "relationships", nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range relationships {
+		if rel := obj.(*models.Relationship); rel.RegionID == id || rel.EndpointA == id || rel.EndpointB == id {
+			view.Relationships = append(view.Relationships, rel)
+		}
+	}
+
+	endpoints, err := list(ctx, client, "externalendpoints", inRegion)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range endpoints {
+		view.ExternalEndpoints = append(view.ExternalEndpoints, obj.(*models.ExternalEndpoint))
+	}
+
+	dynamicNodes, err := list(ctx, client, "dynamicnodes", inRegion)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range dynamicNodes {
+		view.DynamicNodes = append(view.DynamicNodes, obj.(*models.DynamicNode))
+	}
+	return view, nil
+}
+
+// Node retrieves a node and joins its region, the microservices running on it
+// and its dynamic nodes.
+func Node(ctx context.Context, client *operations.Client, id string) (*NodeView, error) {
+	obj, err := resource.Get(ctx, client, "nodes", id)
+	if err != nil {
+		return nil, fmt.Errorf("get nodes %s failed: %s", id, err)
+	}
+	node := obj.(*models.Node)
+	view := &NodeView{Node: node, Capacity: capacity([]*models.Node{node})}
+
+	if node.RegionID != "" {
+		region, err := resource.Get(ctx, client, "regions", node.RegionID)
+		if err != nil && !resource.IsNotFound(err) {
+			return nil, fmt.Errorf("get regions %s failed: %s", node.RegionID, err)
+		}
+		if err == nil {
+			view.Region = region.(*models.Region)
+		}
+	}
+
+	microservices, err := list(ctx, client, "microservices", map[string]string{"node_id": id})
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range microservices {
+		view.Microservices = append(view.Microservices, obj.(*models.Microservice))
+	}
+
+	dynamicNodes, err := list(ctx, client, "dynamicnodes", nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range dynamicNodes {
+		if dyn := obj.(*models.DynamicNode); dyn.NodeID == id {
+			view.DynamicNodes = append(view.DynamicNodes, dyn)
+		}
+	}
+	return view, nil
+}
+
+// Deployment retrieves a deployment and joins its external endpoint and the
+// regions its microservices are placed in or require.
+func Deployment(ctx context.Context, client *operations.Client, name string) (*DeploymentView, error) {
+	obj, err := resource.Get(ctx, client, "deployments", name)
+	if err != nil {
+		return nil, fmt.Errorf("get deployments %s failed: %s", name, err)
+	}
+	depl := obj.(*models.Deployment)
+	view := &DeploymentView{Deployment: depl, Requirements: requirements(depl)}
+
+	if depl.ExternalendpointID != "" {
+		ee, err := resource.Get(ctx, client, "externalendpoints", depl.ExternalendpointID)
+		if err != nil && !resource.IsNotFound(err) {
+			return nil, fmt.Errorf("get externalendpoints %s failed: %s", depl.ExternalendpointID, err)
+		}
+		if err == nil {
+			view.ExternalEndpoint = ee.(*models.ExternalEndpoint)
+		}
+	}
+
+	var regionIDs []string
+	for _, ms := range depl.Microservices {
+		regionIDs = append(regionIDs, ms.RegionID, ms.RegionRequired)
+	}
+	view.Regions, err = regions(ctx, client, regionIDs)
+	if err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// Application retrieves an application and joins its microservices, the
+// nodes they run on and the regions of the nodes.
+func Application(ctx context.Context, client *operations.Client, id string) (*ApplicationView, error) {
+	obj, err := resource.Get(ctx, client, "applications", id)
+	if err != nil {
+		return nil, fmt.Errorf("get applications %s failed: %s", id, err)
+	}
+	app := obj.(*models.Application)
+	view := &ApplicationView{Application: app}
+
+	listed := make(map[string]bool)
+	for _, ms := range app.Microservices {
+		listed[ms.MicroserviceID] = true
+	}
+	microservices, err := list(ctx, client, "microservices", nil)
+	if err != nil {
+		return nil, err
+	}
+	var regionIDs []string
+	nodeIDs := make(map[string]bool)
+	for _, obj := range microservices {
+		ms := obj.(*models.Microservice)
+		if !listed[ms.ID] && ms.ApplicationID != id {
+			continue
+		}
+		view.Microservices = append(view.Microservices, ms)
+		regionIDs = append(regionIDs, ms.RegionID)
+		if ms.NodeID != "" && !nodeIDs[ms.NodeID] {
+			nodeIDs[ms.NodeID] = true
+			obj, err := resource.Get(ctx, client, "nodes", ms.NodeID)
+			if err != nil && !resource.IsNotFound(err) {
+				return nil, fmt.Errorf("get nodes %s failed: %s", ms.NodeID, err)
+			}
+			if err == nil {
+				node := obj.(*models.Node)
+				view.Nodes = append(view.Nodes, node)
+				regionIDs = append(regionIDs, node.RegionID)
+			}
+		}
+	}
+	view.Regions, err = regions(ctx, client, regionIDs)
+	if err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// list retrieves the resources of a type, sorted by identifier.
+func list(ctx context.Context, client *operations.Client, res string, filters map[string]string) ([]interface{}, error) {
+	rt, err := resource.Lookup(res)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := resource.List(ctx, client, rt.Name, filters)
+	if err != nil {
+		return nil, fmt.Errorf("get %s failed: %s", res, err)
+	}
+	sort.SliceStable(objs, func(i, j int) bool { return rt.ID(objs[i]) < rt.ID(objs[j]) })
+	return objs, nil
+}
+
+// regions retrieves the regions with the given identifiers, ignoring the empty
+// and duplicate ones and the regions that do not exist.
+func regions(ctx context.Context, client *operations.Client, ids []string) ([]*models.Region, error) {
+	sort.Strings(ids)
+	var regions []*models.Region
+	for i, id := range ids {
+		if id == "" || (i > 0 && ids[i-1] == id) {
+			continue
+		}
+		obj, err := resource.Get(ctx, client, "regions", id)
+		if err != nil {
+			if resource.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("get regions %s failed: %s", id, err)
+		}
+		regions = append(regions, obj.(*models.Region))
+	}
+	return regions, nil
+}
+
+// capacity adds the capacity of a set of nodes.
+func capacity(nodes []*models.Node) Capacity {
+	var cpu, cpuAvail, mem, memAvail, disk, diskAvail int64
+	var warnings []string
+	add := func(total *int64, node string, field string, quantity string, parse func(string) (int64, error)) {
+		if quantity == "" {
+			return
+		}
+		v, err := parse(quantity)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("node %s, %s: %s", node, field, err))
+			return
+		}
+		*total += v
+	}
+	for _, n := range nodes {
+		add(&cpu, n.ID, "cpu_capacity", n.CPUCapacity, resource.ParseCPU)
+		add(&cpuAvail, n.ID, "cpu_available", n.CPUAvailable, resource.ParseCPU)
+		add(&mem, n.ID, "memory_capacity", n.MemoryCapacity, resource.ParseBytes)
+		add(&memAvail, n.ID, "memory_available", n.MemoryAvailable, resource.ParseBytes)
+		add(&disk, n.ID, "disk_capacity", n.DiskCapacity, resource.ParseBytes)
+		add(&diskAvail, n.ID, "disk_available", n.DiskAvailable, resource.ParseBytes)
+	}
+	return Capacity{
+		Nodes:           len(nodes),
+		CPUCapacity:     resource.FormatCPU(cpu),
+		CPUAvailable:    resource.FormatCPU(cpuAvail),
+		MemoryCapacity:  resource.FormatBytes(mem),
+		MemoryAvailable: resource.FormatBytes(memAvail),
+		DiskCapacity:    resource.FormatBytes(disk),
+		DiskAvailable:   resource.FormatBytes(diskAvail),
+		Warnings:        warnings,
+	}
+}
+
+// requirements adds the requirements of the microservices of a deployment.
+func requirements(depl *models.Deployment) Requirements {
+	var cpu, mem, disk int64
+	req := Requirements{Microservices: len(depl.Microservices)}
+	add := func(total *int64, ms string, field string, quantity string, parse func(string) (int64, error)) {
+		if quantity == "" {
+			return
+		}
+		v, err := parse(quantity)
+		if err != nil {
+			req.Warnings = append(req.Warnings, fmt.Sprintf("microservice %s, %s: %s", ms, field, err))
+			return
+		}
+		*total += v
+	}
+	for _, ms := range depl.Microservices {
+		add(&cpu, ms.Name, "cpu_required", ms.CPURequired, resource.ParseCPU)
+		add(&mem, ms.Name, "memory_required", ms.MemoryRequired, resource.ParseBytes)
+		add(&disk, ms.Name, "disk_required", ms.DiskRequired, resource.ParseBytes)
+		req.PriceRequired += ms.PriceRequired
+		req.PriceComputed += ms.PriceComputed
+	}
+	req.CPURequired = resource.FormatCPU(cpu)
+	req.MemoryRequired = resource.FormatBytes(mem)
+	req.DiskRequired = resource.FormatBytes(disk)
+	return req
+}
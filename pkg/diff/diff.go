@@ -0,0 +1,95 @@
+// Package diff compares two versions of a text, e.g. a resource printed in
+// yaml before and after a change.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines printed around the changes.
+const context = 2
+
+// Lines returns the lines removed from a (prefixed with "-") and added in b
+// (prefixed with "+"), surrounded by a few unchanged lines. It returns an
+// empty string when a and b are equal.
+func Lines(a string, b string) string {
+	x := split(a)
+	y := split(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, line{' ', x[i]})
+			i++
+			j++
+		case j < len(y) && (i == len(x) || lcs[i][j+1] > lcs[i+1][j]):
+			lines = append(lines, line{'+', y[j]})
+			j++
+		default:
+			lines = append(lines, line{'-', x[i]})
+			i++
+		}
+	}
+
+	// print the changes and the unchanged lines close to them
+	show := make([]bool, len(lines))
+	changed := false
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		changed = true
+		for m := k - context; m <= k+context; m++ {
+			if m >= 0 && m < len(lines) {
+				show[m] = true
+			}
+		}
+	}
+	if !changed {
+		return ""
+	}
+	var out strings.Builder
+	for k, l := range lines {
+		if !show[k] {
+			if k == 0 || show[k-1] {
+				out.WriteString("  ...\n")
+			}
+			continue
+		}
+		fmt.Fprintf(&out, "%c %s\n", l.op, l.text)
+	}
+	return out.String()
+}
+
+func split(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,234 @@
+// Package doctor checks the consistency of the resources stored by the
+// FogAtlas API: references to missing resources, orphaned microservices,
+// relationships between unknown regions and inconsistent capacities.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fogatlas/client-go/client/operations"
+	"github.com/fogatlas/client-go/models"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+)
+
+// Kinds of problems.
+const (
+	DanglingReference    = "dangling-reference"
+	OrphanedMicroservice = "orphaned-microservice"
+	RelationshipEndpoint = "relationship-endpoint"
+	Capacity             = "capacity"
+)
+
+// Finding is a problem found on a resource.
+type Finding struct {
+	Resource string `json:"resource"`
+	ID       string `json:"id"`
+	Kind     string `json:"kind"`
+	Problem  string `json:"problem"`
+	// Fix is a command line that solves the problem, or a hint if it cannot
+	// be solved with a single command
+	Fix string `json:"fix"`
+}
+
+// reference is a field of a resource holding the identifiers of resources of
+// another type.
+type reference struct {
+	resource string
+	field    string
+	target   string
+}
+
+// references are the references checked by Check.
+var references = []reference{
+	{"nodes", "region_id", "regions"},
+	{"regions", "relationships.relationship_id", "relationships"},
+	{"microservices", "node_id", "nodes"},
+	{"microservices", "region_id", "regions"},
+	{"microservices", "application_id", "applications"},
+	{"relationships", "region_id", "regions"},
+	{"externalendpoints", "region_id", "regions"},
+	{"dynamicnodes", "region_id", "regions"},
+	{"dynamicnodes", "node_id", "nodes"},
+	{"deployments", "externalendpoint_id", "externalendpoints"},
+	{"deployments", "microservices.region_id", "regions"},
+	{"applications", "microservices.microservice_id", "microservices"},
+}
+
+// inventory is the set of all the resources, by type and identifier.
+type inventory map[string]map[string]interface{}
+
+// ids returns the identifiers of the resources of a type, sorted.
+func (inv inventory) ids(res string) []string {
+	var ids []string
+	for id := range inv[res] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Check retrieves all the resources and returns the problems found, sorted by
+// resource type and identifier.
+func Check(ctx context.Context, client *operations.Client) ([]Finding, error) {
+	inv := make(inventory)
+	for _, rt := range resource.Types {
+		objs, err := resource.List(ctx, client, rt.Name, nil)
+		if err != nil {
+			return nil, fmt.Errorf("get %s failed: %s", rt.Name, err)
+		}
+		inv[rt.Name] = make(map[string]interface{})
+		for _, obj := range objs {
+			inv[rt.Name][rt.ID(obj)] = obj
+		}
+	}
+
+	var findings []Finding
+	findings = append(findings, checkReferences(inv)...)
+	findings = append(findings, checkOrphans(inv)...)
+	findings = append(findings, checkRelationships(inv)...)
+	findings = append(findings, checkCapacities(inv)...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Resource != findings[j].Resource {
+			return findings[i].Resource < findings[j].Resource
+		}
+		return findings[i].ID < findings[j].ID
+	})
+	return findings, nil
+}
+
+// checkReferences finds the references to resources that do not exist.
+func checkReferences(inv inventory) []Finding {
+	var findings []Finding
+	for _, ref := range references {
+		for _, id := range inv.ids(ref.resource) {
+			for _, target := range resource.Field(inv[ref.resource][id], ref.field) {
+				if _, ok := inv[ref.target][target]; ok {
+					continue
+				}
+				f := Finding{
+					Resource: ref.resource,
+					ID:       id,
+					Kind:     DanglingReference,
+					Problem:  fmt.Sprintf("%s %s does not exist", ref.field, target),
+				}
+				if strings.Contains(ref.field, ".") {
+					f.Fix = fmt.Sprintf("remove %s from %s: fogatlasctl edit %s %s", target, ref.field, ref.resource, id)
+				} else {
+					f.Fix = fmt.Sprintf("fogatlasctl delete %s %s, or create %s %s", ref.resource, id, ref.target, target)
+				}
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+// checkOrphans finds the microservices that belong to no application.
+func checkOrphans(inv inventory) []Finding {
+	listed := make(map[string]bool)
+	for _, obj := range inv["applications"] {
+		for _, id := range resource.Field(obj, "microservices.microservice_id") {
+			listed[id] = true
+		}
+	}
+	var findings []Finding
+	for _, id := range inv.ids("microservices") {
+		ms := inv["microservices"][id].(*models.Microservice)
+		if listed[id] {
+			continue
+		}
+		if _, ok := inv["applications"][ms.ApplicationID]; ok {
+			continue
+		}
+		findings = append(findings, Finding{
+			Resource: "microservices",
+			ID:       id,
+			Kind:     OrphanedMicroservice,
+			Problem:  "no application lists the microservice",
+			Fix:      fmt.Sprintf("fogatlasctl delete microservices %s", id),
+		})
+	}
+	return findings
+}
+
+// checkRelationships finds the relationships whose endpoints are not regions.
+func checkRelationships(inv inventory) []Finding {
+	var findings []Finding
+	for _, id := range inv.ids("relationships") {
+		rel := inv["relationships"][id].(*models.Relationship)
+		for _, endpoint := range []struct{ name, region string }{{"endpoint_a", rel.EndpointA}, {"endpoint_b", rel.EndpointB}} {
+			if _, ok := inv["regions"][endpoint.region]; ok {
+				continue
+			}
+			problem := fmt.Sprintf("%s %s is not a region", endpoint.name, endpoint.region)
+			if endpoint.region == "" {
+				problem = fmt.Sprintf("%s is not set", endpoint.name)
+			}
+			findings = append(findings, Finding{
+				Resource: "relationships",
+				ID:       id,
+				Kind:     RelationshipEndpoint,
+				Problem:  problem,
+				Fix:      fmt.Sprintf("fogatlasctl delete relationships %s", id),
+			})
+		}
+	}
+	return findings
+}
+
+// checkCapacities finds the nodes and relationships whose available resources
+// exceed their capacity, or whose quantities cannot be parsed.
+func checkCapacities(inv inventory) []Finding {
+	var findings []Finding
+	capacity := func(res string, id string, name string, capacity int64, available int64, format func(int64) string) {
+		if available <= capacity {
+			return
+		}
+		findings = append(findings, Finding{
+			Resource: res,
+			ID:       id,
+			Kind:     Capacity,
+			Problem:  fmt.Sprintf("%s available (%s) exceeds capacity (%s)", name, format(available), format(capacity)),
+			Fix:      fmt.Sprintf("fogatlasctl set %s %s %s_available=%s", res, id, name, format(capacity)),
+		})
+	}
+	for _, id := range inv.ids("nodes") {
+		node := inv["nodes"][id].(*models.Node)
+		quantities := []struct {
+			name                string
+			capacity, available string
+			parse               func(string) (int64, error)
+			format              func(int64) string
+		}{
+			{"cpu", node.CPUCapacity, node.CPUAvailable, resource.ParseCPU, resource.FormatCPU},
+			{"memory", node.MemoryCapacity, node.MemoryAvailable, resource.ParseBytes, resource.FormatBytes},
+			{"disk", node.DiskCapacity, node.DiskAvailable, resource.ParseBytes, resource.FormatBytes},
+		}
+		for _, q := range quantities {
+			if q.capacity == "" || q.available == "" {
+				continue
+			}
+			c, errC := q.parse(q.capacity)
+			a, errA := q.parse(q.available)
+			if errC != nil || errA != nil {
+				findings = append(findings, Finding{
+					Resource: "nodes",
+					ID:       id,
+					Kind:     Capacity,
+					Problem:  fmt.Sprintf("%s quantities %s and %s cannot be compared", q.name, q.capacity, q.available),
+					Fix:      fmt.Sprintf("use Kubernetes quantities, e.g. 4000m or 8Gi: fogatlasctl set nodes %s %s_capacity=... %s_available=...", id, q.name, q.name),
+				})
+				continue
+			}
+			capacity("nodes", id, q.name, c, a, q.format)
+		}
+	}
+	for _, id := range inv.ids("relationships") {
+		rel := inv["relationships"][id].(*models.Relationship)
+		capacity("relationships", id, "bandwidth", rel.BandwidthCapacity, rel.BandwidthAvailable, func(v int64) string { return fmt.Sprint(v) })
+	}
+	return findings
+}
@@ -0,0 +1,130 @@
+// Package manifest reads the resource files of fogatlasctl: the grouped
+// format of putAll and the kind-tagged manifests, the .tmpl ones rendered as Go
+// templates, and combined with overlays.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+)
+
+// Conf is a set of resources, grouped by type as in the files read by putAll:
+// its json document has a list of resources for each key of resource.Types
+// (see resource.Type.Key).
+type Conf struct {
+	// resources are pointers to the models, by type name
+	resources map[string][]interface{}
+}
+
+// Item is a single resource of a Conf.
+type Item struct {
+	// Resource is the resource type, e.g. nodes
+	Resource string
+	// ID is the identifier used to load the resource
+	ID string
+	// Obj is a pointer to the model, within the Conf
+	Obj interface{}
+}
+
+// Items returns the resources of conf in the order they are loaded, that is
+// the order of resource.Types.
+func (conf *Conf) Items() []Item {
+	var items []Item
+	for _, rt := range resource.Types {
+		for _, obj := range conf.resources[rt.Name] {
+			items = append(items, Item{rt.Name, rt.ID(obj), obj})
+		}
+	}
+	return items
+}
+
+// Find returns the model of the resource with the given type and id, or nil.
+func (conf *Conf) Find(res string, id string) interface{} {
+	for _, item := range conf.Items() {
+		if item.Resource == res && item.ID == id {
+			return item.Obj
+		}
+	}
+	return nil
+}
+
+// Merge appends the resources of other to conf.
+func (conf *Conf) Merge(other *Conf) {
+	for _, rt := range resource.Types {
+		for _, obj := range other.resources[rt.Name] {
+			conf.append(rt.Name, obj)
+		}
+	}
+}
+
+func (conf *Conf) append(res string, obj interface{}) {
+	if conf.resources == nil {
+		conf.resources = make(map[string][]interface{})
+	}
+	conf.resources[res] = append(conf.resources[res], obj)
+}
+
+// CheckDuplicates returns an error if a resource is defined more than once.
+func (conf *Conf) CheckDuplicates() error {
+	seen := make(map[string]bool)
+	for _, item := range conf.Items() {
+		key := item.Resource + "/" + item.ID
+		if seen[key] {
+			return fmt.Errorf("%s %s is defined more than once", item.Resource, item.ID)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// Add decodes a resource of the given type from json and appends it to conf.
+func (conf *Conf) Add(res string, spec []byte) error {
+	rt, err := resource.Lookup(res)
+	if err != nil {
+		return err
+	}
+	obj := rt.NewModel()
+	if err := json.Unmarshal(spec, obj); err != nil {
+		return err
+	}
+	conf.append(rt.Name, obj)
+	return nil
+}
+
+// MarshalJSON encodes conf in the grouped format.
+func (conf *Conf) MarshalJSON() ([]byte, error) {
+	groups := make(map[string][]interface{})
+	for _, rt := range resource.Types {
+		if objs := conf.resources[rt.Name]; len(objs) > 0 {
+			groups[rt.Key()] = objs
+		}
+	}
+	return json.Marshal(groups)
+}
+
+// UnmarshalJSON decodes a document in the grouped format, appending its
+// resources to conf. The keys that are not resource types are ignored.
+func (conf *Conf) UnmarshalJSON(data []byte) error {
+	var groups map[string]json.RawMessage
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return err
+	}
+	for _, rt := range resource.Types {
+		group, ok := groups[rt.Key()]
+		if !ok {
+			continue
+		}
+		var specs []json.RawMessage
+		if err := json.Unmarshal(group, &specs); err != nil {
+			return fmt.Errorf("%s: %s", rt.Key(), err)
+		}
+		for _, spec := range specs {
+			if err := conf.Add(rt.Name, spec); err != nil {
+				return fmt.Errorf("%s: %s", rt.Key(), err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+)
+
+// DescriptorFileKey is the key of a deployment microservice referencing a file
+// that contains its deployment descriptor.
+const DescriptorFileKey = "descriptorFile"
+
+// ResolveDescriptors rewrites the deployment descriptors of a json document so
+// that they are the strings expected by the API. A descriptor can be written
+// as a native yaml object in deployment_descriptor, or referenced with
+// descriptorFile (relative to dir).
+func ResolveDescriptors(data []byte, dir string) ([]byte, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := walkDescriptors(doc, func(ms map[string]interface{}) (bool, error) {
+		file, hasFile := ms[DescriptorFileKey]
+		descr, hasDescr := ms["deployment_descriptor"]
+		if hasFile {
+			if hasDescr {
+				return false, fmt.Errorf("microservice %v has both deployment_descriptor and %s", ms["name"], DescriptorFileKey)
+			}
+			filename, ok := file.(string)
+			if !ok {
+				return false, fmt.Errorf("%s of microservice %v is not a string", DescriptorFileKey, ms["name"])
+			}
+			content, err := ioutil.ReadFile(relativeTo(dir, filename))
+			if err != nil {
+				return false, fmt.Errorf("unable to read descriptor of microservice %v: %s", ms["name"], err)
+			}
+			delete(ms, DescriptorFileKey)
+			ms["deployment_descriptor"] = string(content)
+			return true, nil
+		}
+		if _, ok := descr.(map[string]interface{}); ok {
+			b, err := json.Marshal(descr)
+			if err != nil {
+				return false, err
+			}
+			content, err := yaml.JSONToYAML(b)
+			if err != nil {
+				return false, err
+			}
+			ms["deployment_descriptor"] = string(content)
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil || !changed {
+		return data, err
+	}
+	return json.Marshal(doc)
+}
+
+// ExpandDescriptors replaces the deployment descriptors of a json document
+// with native objects, so that they are readable when printed as yaml.
+// Descriptors that cannot be parsed are left untouched.
+func ExpandDescriptors(data []byte) ([]byte, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := walkDescriptors(doc, func(ms map[string]interface{}) (bool, error) {
+		descr, ok := ms["deployment_descriptor"].(string)
+		if !ok {
+			return false, nil
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(descr), &obj); err != nil || obj == nil {
+			return false, nil
+		}
+		ms["deployment_descriptor"] = obj
+		return true, nil
+	})
+	if err != nil || !changed {
+		return data, err
+	}
+	return json.Marshal(doc)
+}
+
+// decodeJSON decodes a json document keeping numbers as they are written.
+func decodeJSON(data []byte) (interface{}, error) {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// walkDescriptors calls fn on every object of doc that can hold a deployment
+// descriptor, that is an object with a deployment_descriptor or a
+// descriptorFile key. It reports whether fn changed any of them.
+func walkDescriptors(doc interface{}, fn func(map[string]interface{}) (bool, error)) (bool, error) {
+	changed := false
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		_, hasDescr := v["deployment_descriptor"]
+		_, hasFile := v[DescriptorFileKey]
+		if hasDescr || hasFile {
+			return fn(v)
+		}
+		for _, child := range v {
+			c, err := walkDescriptors(child, fn)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+	case []interface{}:
+		for _, child := range v {
+			c, err := walkDescriptors(child, fn)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+	}
+	return changed, nil
+}
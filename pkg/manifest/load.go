@@ -0,0 +1,250 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/ghodss/yaml"
+)
+
+// Options are the sources of a set of resources.
+type Options struct {
+	// Files are files, directories (scanned recursively for yaml and json
+	// files and their templates), glob patterns or "-" for stdin
+	Files []string
+	// Overlay is a directory containing an overlay.yaml
+	Overlay string
+	// ValueFiles are yaml files of values for the templates
+	ValueFiles []string
+	// Values are values for the templates, e.g. given on the command line, that
+	// take precedence over the ones of ValueFiles and of the overlay
+	Values map[string]interface{}
+}
+
+// Load reads the resources of an overlay and of a list of files, rendering the
+// templates with the values given in opts.
+func Load(opts Options) (*Conf, error) {
+	if len(opts.Files) == 0 && opts.Overlay == "" {
+		return nil, fmt.Errorf("neither files nor overlay given")
+	}
+	values := make(map[string]interface{})
+	for _, filename := range opts.ValueFiles {
+		if err := ReadValues(filename, values); err != nil {
+			return nil, err
+		}
+	}
+	overrides := opts.Values
+	if overrides == nil {
+		overrides = make(map[string]interface{})
+	}
+	MergeValues(values, overrides)
+
+	conf := &Conf{}
+	if opts.Overlay != "" {
+		var err error
+		conf, err = LoadOverlay(opts.Overlay, values, overrides)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Files) > 0 {
+		other, err := LoadFiles(opts.Files, values)
+		if err != nil {
+			return nil, err
+		}
+		conf.Merge(other)
+		if err := conf.CheckDuplicates(); err != nil {
+			return nil, err
+		}
+	}
+	return conf, nil
+}
+
+// LoadFiles reads the resources described by a list of inputs and merges them
+// in a single Conf. An input can be a file, a directory (scanned recursively
+// for yaml and json files and their templates), a glob pattern or "-" for
+// stdin. The templates are rendered with the given values (see RenderFile).
+// Each file can contain several yaml documents separated by "---", each one
+// either in the grouped format or a kind-tagged manifest.
+func LoadFiles(inputs []string, values map[string]interface{}) (*Conf, error) {
+	files, err := ExpandInputs(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &Conf{}
+	sources := make(map[string]string)
+	for _, filename := range files {
+		data, err := RenderFile(filename, values)
+		if err != nil {
+			return nil, err
+		}
+		docs := SplitDocuments(data)
+		for i, doc := range docs {
+			source := filename
+			if len(docs) > 1 {
+				source = fmt.Sprintf("%s (document %d)", filename, i+1)
+			}
+			part, err := parseDocument(doc, filepath.Dir(filename))
+			if err != nil {
+				return nil, fmt.Errorf("wrong file format in %s: %s", source, err)
+			}
+			for _, item := range part.Items() {
+				key := item.Resource + "/" + item.ID
+				if prev, ok := sources[key]; ok {
+					return nil, fmt.Errorf("%s %s is defined both in %s and in %s", item.Resource, item.ID, prev, source)
+				}
+				sources[key] = source
+			}
+			conf.Merge(part)
+		}
+	}
+	return conf, nil
+}
+
+// LoadResources reads single resources of the given type, one per file, in
+// the format accepted by put, either plain or as kind-tagged manifests.
+func LoadResources(res string, inputs []string) ([]interface{}, error) {
+	files, err := ExpandInputs(inputs)
+	if err != nil {
+		return nil, err
+	}
+	var objs []interface{}
+	for _, filename := range files {
+		obj := resource.NewModel(res)
+		if obj == nil {
+			return nil, fmt.Errorf("resource specified (%s) is unknown", res)
+		}
+		data, err := ReadInput(filename)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read file %s: %s", filename, err)
+		}
+		data, err = yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("wrong file format in %s: %s", filename, err)
+		}
+		data, err = ResolveDescriptors(data, filepath.Dir(filename))
+		if err != nil {
+			return nil, fmt.Errorf("wrong deployment descriptor in %s: %s", filename, err)
+		}
+		if err := DecodeResource(data, res, obj); err != nil {
+			return nil, fmt.Errorf("wrong file format in %s: %s", filename, err)
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// ExpandInputs resolves directories and glob patterns into a list of files.
+// A file reached through several inputs is listed only once.
+func ExpandInputs(inputs []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(filename string) {
+		if !seen[filepath.Clean(filename)] {
+			seen[filepath.Clean(filename)] = true
+			files = append(files, filename)
+		}
+	}
+	stdin := false
+	for _, input := range inputs {
+		if input == "-" {
+			if stdin {
+				return nil, fmt.Errorf("stdin can be read only once")
+			}
+			stdin = true
+			files = append(files, input)
+			continue
+		}
+		matches := []string{input}
+		if strings.ContainsAny(input, "*?[") {
+			var err error
+			matches, err = filepath.Glob(input)
+			if err != nil {
+				return nil, fmt.Errorf("wrong pattern %s: %s", input, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no file matches %s", input)
+			}
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read file %s: %s", match, err)
+			}
+			if !info.IsDir() {
+				add(match)
+				continue
+			}
+			dirFiles, err := walkDir(match)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read directory %s: %s", match, err)
+			}
+			for _, filename := range dirFiles {
+				add(filename)
+			}
+		}
+	}
+	return files, nil
+}
+
+// walkDir returns the yaml and json files found under dir, and the templates
+// of such files, sorted by path.
+func walkDir(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(strings.TrimSuffix(path, TemplateExt))) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// ReadInput reads a file, or stdin if filename is "-".
+func ReadInput(filename string) ([]byte, error) {
+	if filename == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(filename)
+}
+
+// SplitDocuments splits a yaml stream in its documents, skipping empty ones.
+func SplitDocuments(data []byte) [][]byte {
+	var docs [][]byte
+	var current bytes.Buffer
+	flush := func() {
+		if len(bytes.TrimSpace(current.Bytes())) > 0 {
+			docs = append(docs, append([]byte(nil), current.Bytes()...))
+		}
+		current.Reset()
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimRight(line, " \t") == "---" {
+			flush()
+			continue
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+	}
+	flush()
+	return docs
+}
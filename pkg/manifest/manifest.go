@@ -0,0 +1,138 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/ghodss/yaml"
+)
+
+// APIVersion is the apiVersion of the kind-tagged manifests.
+const APIVersion = "fogatlas/v2"
+
+// Manifest is a single resource described in the Kubernetes style:
+//
+//	kind: Region
+//	apiVersion: fogatlas/v2
+//	spec:
+//	  id: "CLOUD"
+//	  tier: 0
+type Manifest struct {
+	Kind       string          `json:"kind"`
+	APIVersion string          `json:"apiVersion"`
+	Spec       json.RawMessage `json:"spec"`
+}
+
+// KindOf returns the manifest kind of a resource type, see resource.Type.Kind.
+func KindOf(res string) string {
+	rt, err := resource.Lookup(res)
+	if err != nil {
+		return ""
+	}
+	return rt.Kind
+}
+
+// isManifest reports whether a json document is a kind-tagged manifest.
+func isManifest(data []byte) bool {
+	var probe struct {
+		Kind string `json:"kind"`
+	}
+	return json.Unmarshal(data, &probe) == nil && probe.Kind != ""
+}
+
+// parseManifest decodes a kind-tagged manifest and returns its resource type.
+func parseManifest(data []byte) (*resource.Type, *Manifest, error) {
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, nil, err
+	}
+	rt := resource.LookupKind(m.Kind)
+	if rt == nil {
+		return nil, nil, fmt.Errorf("unknown kind %s", m.Kind)
+	}
+	if m.APIVersion != APIVersion {
+		return nil, nil, fmt.Errorf("unsupported apiVersion %q for %s, expected %s", m.APIVersion, m.Kind, APIVersion)
+	}
+	if len(m.Spec) == 0 {
+		return nil, nil, fmt.Errorf("%s has no spec", m.Kind)
+	}
+	return rt, m, nil
+}
+
+// DecodeResource unmarshals a json document in the model obj of the given
+// resource type. The document can either be the plain resource or a
+// kind-tagged manifest whose kind matches the resource type.
+func DecodeResource(data []byte, res string, obj interface{}) error {
+	if !isManifest(data) {
+		return json.Unmarshal(data, obj)
+	}
+	rt, m, err := parseManifest(data)
+	if err != nil {
+		return err
+	}
+	if rt.Name != res {
+		return fmt.Errorf("manifest of kind %s cannot be used for %s", m.Kind, res)
+	}
+	return json.Unmarshal(m.Spec, obj)
+}
+
+// parseDocument decodes a yaml document that is either in the grouped format
+// of putAll or a kind-tagged manifest. Descriptor files are resolved relatively
+// to dir.
+func parseDocument(doc []byte, dir string) (*Conf, error) {
+	data, err := yaml.YAMLToJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	data, err = ResolveDescriptors(data, dir)
+	if err != nil {
+		return nil, err
+	}
+	conf := &Conf{}
+	if !isManifest(data) {
+		if err := json.Unmarshal(data, conf); err != nil {
+			return nil, err
+		}
+		return conf, nil
+	}
+	rt, m, err := parseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := conf.Add(rt.Name, m.Spec); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// ToManifests renders the resources of conf as a stream of kind-tagged manifests.
+func (conf *Conf) ToManifests() ([]byte, error) {
+	var docs []string
+	for _, item := range conf.Items() {
+		spec, err := json.Marshal(item.Obj)
+		if err != nil {
+			return nil, err
+		}
+		out, err := yaml.Marshal(&Manifest{Kind: KindOf(item.Resource), APIVersion: APIVersion, Spec: spec})
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, string(out))
+	}
+	return []byte("---\n" + strings.Join(docs, "---\n")), nil
+}
+
+// Encode renders the resources of conf either in the grouped format or as
+// kind-tagged manifests.
+func (conf *Conf) Encode(format string) ([]byte, error) {
+	switch format {
+	case "kind":
+		return conf.ToManifests()
+	case "grouped":
+		return yaml.Marshal(conf)
+	default:
+		return nil, fmt.Errorf("output format specified (%s) is unknown", format)
+	}
+}
@@ -0,0 +1,198 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/ghodss/yaml"
+)
+
+// OverlayFile is the name of the file describing an overlay.
+const OverlayFile = "overlay.yaml"
+
+// Overlay describes an environment built on top of a set of base manifests:
+//
+//	bases:
+//	  - ../base
+//	values:
+//	  - values.yaml
+//	patches:
+//	  - nodes.yaml
+//
+// Paths are relative to the overlay directory. Patches are merged in the base
+// resources with the same type and id (json merge patch semantics), or added
+// if no such resource exists.
+type Overlay struct {
+	Bases   []string `json:"bases"`
+	Values  []string `json:"values,omitempty"`
+	Patches []string `json:"patches,omitempty"`
+}
+
+// LoadOverlay loads the base manifests of an overlay and applies its patches.
+// The values of the overlay files are overridden by values, then by overrides.
+func LoadOverlay(dir string, values map[string]interface{}, overrides map[string]interface{}) (*Conf, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, OverlayFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read overlay %s: %s", dir, err)
+	}
+	ov := &Overlay{}
+	if err := yaml.Unmarshal(data, ov); err != nil {
+		return nil, fmt.Errorf("wrong file format in %s: %s", filepath.Join(dir, OverlayFile), err)
+	}
+	if len(ov.Bases) == 0 {
+		return nil, fmt.Errorf("overlay %s has no bases", dir)
+	}
+
+	ovValues := make(map[string]interface{})
+	for _, filename := range ov.Values {
+		if err := ReadValues(relativeTo(dir, filename), ovValues); err != nil {
+			return nil, err
+		}
+	}
+	MergeValues(ovValues, values)
+	MergeValues(ovValues, overrides)
+
+	var bases []string
+	for _, base := range ov.Bases {
+		bases = append(bases, relativeTo(dir, base))
+	}
+	conf, err := LoadFiles(bases, ovValues)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, patch := range ov.Patches {
+		filename := relativeTo(dir, patch)
+		data, err := RenderFile(filename, ovValues)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range SplitDocuments(data) {
+			if err := conf.ApplyPatch(doc, filepath.Dir(filename)); err != nil {
+				return nil, fmt.Errorf("unable to apply patch %s: %s", filename, err)
+			}
+		}
+	}
+	return conf, nil
+}
+
+// relativeTo resolves path relatively to dir, unless it is absolute.
+func relativeTo(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// ApplyPatch merges the resources of a yaml document in the matching resources
+// of conf. Descriptor files are resolved relatively to dir.
+func (conf *Conf) ApplyPatch(doc []byte, dir string) error {
+	data, err := yaml.YAMLToJSON(doc)
+	if err != nil {
+		return err
+	}
+	data, err = ResolveDescriptors(data, dir)
+	if err != nil {
+		return err
+	}
+	patches, err := rawResources(data)
+	if err != nil {
+		return err
+	}
+	for _, patch := range patches {
+		target := conf.Find(patch.rt.Name, patch.id)
+		if target == nil {
+			if err := conf.Add(patch.rt.Name, patch.data); err != nil {
+				return err
+			}
+			continue
+		}
+		orig, err := json.Marshal(target)
+		if err != nil {
+			return err
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(orig, &fields); err != nil {
+			return err
+		}
+		var patchFields map[string]interface{}
+		if err := json.Unmarshal(patch.data, &patchFields); err != nil {
+			return err
+		}
+		merged, err := json.Marshal(MergePatch(fields, patchFields))
+		if err != nil {
+			return err
+		}
+		v := reflect.ValueOf(target).Elem()
+		v.Set(reflect.Zero(v.Type()))
+		if err := json.Unmarshal(merged, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MergePatch applies a json merge patch (RFC 7386) to doc.
+func MergePatch(doc map[string]interface{}, patch map[string]interface{}) map[string]interface{} {
+	for key, value := range patch {
+		if value == nil {
+			delete(doc, key)
+			continue
+		}
+		patchMap, patchOk := value.(map[string]interface{})
+		docMap, docOk := doc[key].(map[string]interface{})
+		if patchOk {
+			if !docOk {
+				docMap = make(map[string]interface{})
+			}
+			doc[key] = MergePatch(docMap, patchMap)
+			continue
+		}
+		doc[key] = value
+	}
+	return doc
+}
+
+// rawResource is a resource kept as json, so that the fields that are not set
+// can be told apart from the ones set to their zero value.
+type rawResource struct {
+	rt   *resource.Type
+	id   string
+	data json.RawMessage
+}
+
+// rawResources returns the resources of a json document that is either in the
+// grouped format or a kind-tagged manifest.
+func rawResources(data []byte) ([]rawResource, error) {
+	var specs []rawResource
+	if isManifest(data) {
+		rt, m, err := parseManifest(data)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, rawResource{rt: rt, data: m.Spec})
+	} else {
+		var groups map[string][]json.RawMessage
+		if err := json.Unmarshal(data, &groups); err != nil {
+			return nil, err
+		}
+		for key, group := range groups {
+			rt := resource.LookupFileKey(key)
+			if rt == nil {
+				return nil, fmt.Errorf("unknown resource type %s", key)
+			}
+			for _, spec := range group {
+				specs = append(specs, rawResource{rt: rt, data: spec})
+			}
+		}
+	}
+	for i := range specs {
+		// same identifiers used by putAll, see Conf.Items
+		specs[i].id = specs[i].rt.ID(specs[i].data)
+	}
+	return specs, nil
+}
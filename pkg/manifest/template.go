@@ -0,0 +1,154 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+)
+
+// ReadValues reads a yaml file of values and merges it in values.
+func ReadValues(filename string, values map[string]interface{}) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("unable to read file %s: %s", filename, err)
+	}
+	fileValues := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &fileValues); err != nil {
+		return fmt.Errorf("wrong file format in %s: %s", filename, err)
+	}
+	MergeValues(values, fileValues)
+	return nil
+}
+
+// SetValue parses a key=value pair and stores it in values. Dots in the key
+// create nested maps.
+func SetValue(values map[string]interface{}, kv string) error {
+	parts := strings.SplitN(kv, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("wrong value %s, expected key=value", kv)
+	}
+	keys := strings.Split(parts[0], ".")
+	m := values
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = parts[1]
+	return nil
+}
+
+// MergeValues merges src in dst recursively, src taking precedence.
+func MergeValues(dst map[string]interface{}, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcOk := value.(map[string]interface{})
+		dstMap, dstOk := dst[key].(map[string]interface{})
+		if srcOk && dstOk {
+			MergeValues(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// TemplateExt is the suffix of the manifests rendered as Go templates, e.g.
+// regions.yaml.tmpl.
+const TemplateExt = ".tmpl"
+
+// IsTemplate reports whether a manifest is a template, that is whether its
+// name ends with TemplateExt.
+func IsTemplate(filename string) bool {
+	return strings.HasSuffix(filename, TemplateExt)
+}
+
+// RenderFile reads a manifest and, if it is a template (see IsTemplate),
+// renders it with the given values. Other files, stdin included, are returned
+// as they are. A value used by the template but not given is an error, unless
+// it is read with default.
+func RenderFile(filename string, values map[string]interface{}) ([]byte, error) {
+	data, err := ReadInput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file %s: %s", filename, err)
+	}
+	if filename == "-" || !IsTemplate(filename) {
+		return data, nil
+	}
+	tmpl, err := template.New(filepath.Base(filename)).
+		Option("missingkey=error").
+		Funcs(templateFuncs(values)).
+		Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("wrong template in %s: %s", filename, err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, values); err != nil {
+		return nil, fmt.Errorf("unable to render %s: %s", filename, err)
+	}
+	return out.Bytes(), nil
+}
+
+// templateFuncs returns the functions available in the manifest templates.
+// default takes the name of the value, dotted for nested values, since a
+// missing value cannot be read with .name.
+func templateFuncs(values map[string]interface{}) template.FuncMap {
+	return template.FuncMap{
+		"default": func(def interface{}, key string) interface{} {
+			value, ok := lookupValue(values, key)
+			if !ok || isZero(value) {
+				return def
+			}
+			return value
+		},
+		"quote": func(value interface{}) string {
+			return fmt.Sprintf("%q", fmt.Sprint(value))
+		},
+	}
+}
+
+// lookupValue returns the value of a dotted key, and whether it is set.
+func lookupValue(values map[string]interface{}, key string) (interface{}, bool) {
+	var value interface{} = values
+	for _, name := range strings.Split(key, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[name]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// isZero reports whether a value is empty: nil, false, 0, an empty string or
+// an empty list or map.
+func isZero(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,331 @@
+// Package mock is an in-memory implementation of the endpoints of the
+// FogAtlas API used by fogatlasctl, to run scripts and tests without a live
+// FogAtlas.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fogatlas/fogatlasctl/pkg/api"
+	"github.com/fogatlas/fogatlasctl/pkg/manifest"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+)
+
+// Options configure a Server.
+type Options struct {
+	// Latency delays every response.
+	Latency time.Duration
+	// ErrorRate is the fraction of the requests, between 0 and 1, answered
+	// with ErrorStatus (500 if 0) instead of being served.
+	ErrorRate   float64
+	ErrorStatus int
+	// ErrorMatch restricts the injected errors to the requests it reports,
+	// e.g. to make a given put fail; nil selects all the requests.
+	ErrorMatch func(r *http.Request) bool
+	// TransitionDelay is the time a deployment spends in each status before
+	// moving to the next one, see Transitions.
+	TransitionDelay time.Duration
+	// Log receives a line for every request, nil discards them.
+	Log io.Writer
+}
+
+// Transitions is the state machine of the deployments: a deployment whose
+// status is a key moves to the value after Options.TransitionDelay, e.g. a
+// deployment put or patched as todeploy is deploying, then deployed.
+var Transitions = map[string]string{
+	"todeploy":    "deploying",
+	"deploying":   "deployed",
+	"toundeploy":  "undeploying",
+	"undeploying": "undeployed",
+}
+
+// Server serves the API from memory. The resources are kept as the json
+// documents received, by type and identifier.
+type Server struct {
+	opts Options
+
+	mu    sync.Mutex
+	store map[string]map[string]map[string]interface{}
+	// generation counts the status changes of each deployment, so that a
+	// pending transition is dropped when the status changed in between
+	generation map[string]int
+}
+
+// NewServer returns a Server without resources.
+func NewServer(opts Options) *Server {
+	if opts.ErrorStatus == 0 {
+		opts.ErrorStatus = http.StatusInternalServerError
+	}
+	s := &Server{
+		opts:       opts,
+		store:      make(map[string]map[string]map[string]interface{}),
+		generation: make(map[string]int),
+	}
+	for _, rt := range resource.Types {
+		s.store[rt.Name] = make(map[string]map[string]interface{})
+	}
+	return s
+}
+
+// Seed adds the resources of conf, e.g. loaded from the files of putAll.
+func (s *Server) Seed(conf *manifest.Conf) error {
+	for _, item := range conf.Items() {
+		doc, err := toDocument(item.Obj)
+		if err != nil {
+			return fmt.Errorf("%s %s: %s", item.Resource, item.ID, err)
+		}
+		s.put(item.Resource, item.ID, doc)
+	}
+	return nil
+}
+
+// Resources returns the json documents of the resources of a type, sorted by
+// identifier.
+func (s *Server) Resources(res string) []map[string]interface{} {
+	s.mu.Lock()
+	data, err := json.Marshal(s.list(res, nil))
+	s.mu.Unlock()
+	var docs []map[string]interface{}
+	if err == nil {
+		json.Unmarshal(data, &docs)
+	}
+	return docs
+}
+
+func toDocument(obj interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	err = json.Unmarshal(data, &doc)
+	return doc, err
+}
+
+// ServeHTTP serves the requests for the resources under api.BasePath.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.serve(rec, r)
+	if s.opts.Log != nil {
+		fmt.Fprintf(s.opts.Log, "%s %s %s %d\n", time.Now().Format("15:04:05.000"), r.Method, r.URL.RequestURI(), rec.status)
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Latency > 0 {
+		time.Sleep(s.opts.Latency)
+	}
+	if !strings.HasPrefix(r.URL.Path, api.BasePath+"/") {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, api.BasePath+"/"), "/", 2)
+	res := parts[0]
+	if _, ok := s.store[res]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if s.opts.ErrorRate > 0 && (s.opts.ErrorMatch == nil || s.opts.ErrorMatch(r)) && rand.Float64() < s.opts.ErrorRate {
+		http.Error(w, "error injected by the mock server", s.opts.ErrorStatus)
+		return
+	}
+
+	if len(parts) == 1 || parts[1] == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.mu.Lock()
+		data, err := json.Marshal(map[string]interface{}{res: s.list(res, r.URL.Query())})
+		s.mu.Unlock()
+		writeJSON(w, data, err)
+		return
+	}
+
+	id := parts[1]
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		doc, ok := s.store[res][id]
+		data, err := json.Marshal(doc)
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, fmt.Sprintf("%s %s not found", res, id), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, data, err)
+	case http.MethodPut:
+		doc, err := readDocument(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		key := "id"
+		if rt, err := resource.Lookup(res); err == nil {
+			key = rt.IDKey()
+		}
+		if _, ok := doc[key]; !ok {
+			doc[key] = id
+		}
+		s.put(res, id, doc)
+	case http.MethodPatch:
+		if res != "deployments" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		patch, err := readDocument(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		status, _ := patch["status"].(string)
+		s.mu.Lock()
+		doc, ok := s.store[res][id]
+		if ok {
+			doc["status"] = status
+			s.changed(id, doc)
+		}
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, fmt.Sprintf("%s %s not found", res, id), http.StatusNotFound)
+		}
+	case http.MethodDelete:
+		s.mu.Lock()
+		_, ok := s.store[res][id]
+		delete(s.store[res], id)
+		if res == "deployments" {
+			s.generation[id]++
+		}
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, fmt.Sprintf("%s %s not found", res, id), http.StatusNotFound)
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// list returns the resources of a type matching the query filters, e.g.
+// region_id, sorted by identifier. It must be called with the lock held.
+func (s *Server) list(res string, query map[string][]string) []map[string]interface{} {
+	var ids []string
+	for id := range s.store[res] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	docs := []map[string]interface{}{}
+	for _, id := range ids {
+		doc := s.store[res][id]
+		match := true
+		for key, values := range query {
+			if value, _ := doc[key].(string); len(values) > 0 && value != values[0] {
+				match = false
+			}
+		}
+		if match {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+func (s *Server) put(res string, id string, doc map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store[res][id] = doc
+	if res == "deployments" {
+		s.changed(id, doc)
+	}
+}
+
+// changed schedules the next transition of a deployment whose status was
+// set. It must be called with the lock held.
+func (s *Server) changed(name string, doc map[string]interface{}) {
+	s.generation[name]++
+	status, _ := doc["status"].(string)
+	next, ok := Transitions[status]
+	if !ok {
+		return
+	}
+	generation := s.generation[name]
+	time.AfterFunc(s.opts.TransitionDelay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		doc, ok := s.store["deployments"][name]
+		if !ok || s.generation[name] != generation {
+			return
+		}
+		doc["status"] = next
+		if next == "deployed" {
+			s.place(doc)
+		}
+		s.changed(name, doc)
+	})
+}
+
+// place sets the region of the microservices of a deployed deployment: the
+// region required, or else the first region. It must be called with the lock
+// held.
+func (s *Server) place(doc map[string]interface{}) {
+	var first string
+	if regions := s.list("regions", nil); len(regions) > 0 {
+		first, _ = regions[0]["id"].(string)
+	}
+	microservices, _ := doc["microservices"].([]interface{})
+	for _, ms := range microservices {
+		ms, ok := ms.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if required, _ := ms["region_required"].(string); required != "" {
+			ms["region_id"] = required
+		} else if first != "" {
+			ms["region_id"] = first
+		}
+	}
+}
+
+func readDocument(r *http.Request) (map[string]interface{}, error) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("body not valid: %s", err)
+	}
+	if doc == nil {
+		doc = make(map[string]interface{})
+	}
+	return doc, nil
+}
+
+// writeJSON writes a json document, or the error met encoding it.
+func writeJSON(w http.ResponseWriter, data []byte, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// statusRecorder keeps the status of a response for the log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,57 @@
+// Package printer formats the resources of the FogAtlas API as tables, yaml
+// or json.
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/fogatlas/fogatlasctl/pkg/manifest"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/ghodss/yaml"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Tables prints resources as the tables of their type.
+func Tables(w io.Writer, rt *resource.Type, objs []interface{}) {
+	for _, t := range rt.Tables {
+		var data [][]string
+		for _, obj := range objs {
+			data = append(data, t.Rows(obj)...)
+		}
+		if t.Title != "" {
+			fmt.Fprintf(w, "%s\n", t.Title)
+		}
+		table := tablewriter.NewWriter(w)
+		table.SetHeader(t.Header)
+		table.AppendBulk(data)
+		table.Render()
+	}
+}
+
+// Object prints a model, or any value made of models, in yaml or json format.
+// In yaml, deployment descriptors are printed as native objects.
+func Object(w io.Writer, obj interface{}, format string) error {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode response: %s", err)
+	}
+	switch format {
+	case "json":
+		fmt.Fprintln(w, string(data))
+	case "yaml":
+		data, err = manifest.ExpandDescriptors(data)
+		if err != nil {
+			return fmt.Errorf("unable to encode response: %s", err)
+		}
+		out, err := yaml.JSONToYAML(data)
+		if err != nil {
+			return fmt.Errorf("unable to encode response: %s", err)
+		}
+		fmt.Fprint(w, string(out))
+	default:
+		return fmt.Errorf("output format specified (%s) is unknown", format)
+	}
+	return nil
+}
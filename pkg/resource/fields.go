@@ -0,0 +1,207 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fogatlas/client-go/client/operations"
+)
+
+// Field returns the values of a field of a model given the path of its json
+// name, e.g. prices.cpu.unit_price. Lists are traversed, so a field can have
+// several values, e.g. microservices.microservice_id of an application. It
+// returns nil if the field is not set, that is a nil pointer or an empty
+// string; numbers are always set, so tier=0 can be selected.
+func Field(obj interface{}, path string) []string {
+	return fieldValues(reflect.ValueOf(obj), strings.Split(path, "."))
+}
+
+// fieldValues returns the values found following the json names keys from v.
+func fieldValues(v reflect.Value, keys []string) []string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		var values []string
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, fieldValues(v.Index(i), keys)...)
+		}
+		return values
+	case reflect.Struct:
+		if len(keys) == 0 {
+			b, err := json.Marshal(v.Interface())
+			if err != nil {
+				return nil
+			}
+			return []string{string(b)}
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath == "" && strings.Split(f.Tag.Get("json"), ",")[0] == keys[0] {
+				return fieldValues(v.Field(i), keys[1:])
+			}
+		}
+		return nil
+	case reflect.Map:
+		if len(keys) == 0 || v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		return fieldValues(v.MapIndex(reflect.ValueOf(keys[0])), keys[1:])
+	case reflect.Invalid:
+		return nil
+	}
+	if len(keys) > 0 {
+		return nil
+	}
+	if v.Kind() == reflect.String && v.Len() == 0 {
+		return nil
+	}
+	return []string{fmt.Sprint(v.Interface())}
+}
+
+// FieldType returns the type of a field of a model given the path of its json
+// name, as Field does, nil if the model has no such field. Lists and maps are
+// traversed, a numeric key selecting an element of a list.
+func FieldType(obj interface{}, path string) reflect.Type {
+	t := reflect.TypeOf(obj)
+	for _, key := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array:
+			t = t.Elem()
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			if _, err := strconv.Atoi(key); err == nil {
+				continue
+			}
+			if t.Kind() != reflect.Struct {
+				return nil
+			}
+		case reflect.Map:
+			t = t.Elem()
+			continue
+		}
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		var found reflect.Type
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath == "" && strings.Split(f.Tag.Get("json"), ",")[0] == key {
+				found = f.Type
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		t = found
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// Resolver returns the fields of models following the references to other
+// resources: if a model has no field region but a region_id, the path
+// region.tier is the tier of the region it references. The referenced
+// resources are retrieved once per type.
+type Resolver struct {
+	ctx    context.Context
+	client *operations.Client
+	cache  map[string]map[string]interface{}
+	err    error
+}
+
+// NewResolver returns a Resolver retrieving the referenced resources with client.
+func NewResolver(ctx context.Context, client *operations.Client) *Resolver {
+	return &Resolver{ctx: ctx, client: client, cache: make(map[string]map[string]interface{})}
+}
+
+// Field returns the values of a field of a model, see Field.
+func (r *Resolver) Field(obj interface{}, path string) []string {
+	if values := Field(obj, path); values != nil {
+		return values
+	}
+	parts := strings.SplitN(path, ".", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	rt, err := Lookup(parts[0])
+	if err != nil {
+		return nil
+	}
+	var values []string
+	for _, id := range Field(obj, parts[0]+"_id") {
+		if ref := r.resource(rt, id); ref != nil {
+			values = append(values, r.Field(ref, parts[1])...)
+		}
+	}
+	return values
+}
+
+// Err returns the first error met retrieving the referenced resources.
+func (r *Resolver) Err() error {
+	return r.err
+}
+
+// resource returns the resource of the given type and id, nil if not found.
+func (r *Resolver) resource(rt *Type, id string) interface{} {
+	byID, ok := r.cache[rt.Name]
+	if !ok {
+		byID = make(map[string]interface{})
+		objs, err := rt.List(r.ctx, r.client, func(string) *string { return nil })
+		if err != nil && r.err == nil {
+			r.err = fmt.Errorf("get %s failed: %s", rt.Name, err)
+		}
+		for _, obj := range objs {
+			byID[rt.ID(obj)] = obj
+		}
+		r.cache[rt.Name] = byID
+	}
+	return byID[id]
+}
+
+// SortBy sorts models by the first value of a field, numerically when both
+// values are numbers. Models without the field come last.
+func SortBy(objs []interface{}, path string, field func(obj interface{}, path string) []string) {
+	keys := make(map[int]string, len(objs))
+	set := make(map[int]bool, len(objs))
+	idx := make([]int, len(objs))
+	for i, obj := range objs {
+		idx[i] = i
+		if values := field(obj, path); len(values) > 0 {
+			keys[i], set[i] = values[0], true
+		}
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		i, j := idx[a], idx[b]
+		if !set[i] || !set[j] {
+			return set[i] && !set[j]
+		}
+		x, errX := strconv.ParseFloat(keys[i], 64)
+		y, errY := strconv.ParseFloat(keys[j], 64)
+		if errX == nil && errY == nil {
+			return x < y
+		}
+		return keys[i] < keys[j]
+	})
+	sorted := make([]interface{}, len(objs))
+	for k, i := range idx {
+		sorted[k] = objs[i]
+	}
+	copy(objs, sorted)
+}
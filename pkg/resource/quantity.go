@@ -0,0 +1,80 @@
+package resource
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseCPU parses a Kubernetes cpu quantity ("0.5", "500m", "2") in millicores.
+func ParseCPU(quantity string) (int64, error) {
+	if strings.HasSuffix(quantity, "m") {
+		v, err := strconv.ParseInt(strings.TrimSuffix(quantity, "m"), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("wrong cpu quantity %s", quantity)
+		}
+		return v, nil
+	}
+	v, err := strconv.ParseFloat(quantity, 64)
+	if err != nil {
+		return 0, fmt.Errorf("wrong cpu quantity %s", quantity)
+	}
+	return int64(math.Ceil(v * 1000)), nil
+}
+
+// byteSuffixes are the multipliers of the Kubernetes memory quantities.
+var byteSuffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40}, {"Pi", 1 << 50}, {"Ei", 1 << 60},
+	{"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12}, {"P", 1e15}, {"E", 1e18},
+}
+
+// ParseBytes parses a Kubernetes memory or storage quantity ("800Mi", "1G") in bytes.
+func ParseBytes(quantity string) (int64, error) {
+	multiplier := 1.0
+	number := quantity
+	for _, s := range byteSuffixes {
+		if strings.HasSuffix(quantity, s.suffix) {
+			multiplier = s.multiplier
+			number = strings.TrimSuffix(quantity, s.suffix)
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("wrong quantity %s", quantity)
+	}
+	return int64(math.Ceil(v * multiplier)), nil
+}
+
+// FormatCPU formats millicores as a cpu quantity.
+func FormatCPU(milli int64) string {
+	return strconv.FormatInt(milli, 10) + "m"
+}
+
+// FormatMebibytes formats bytes as a quantity in Mi, rounding up.
+func FormatMebibytes(bytes int64) string {
+	return strconv.FormatInt((bytes+(1<<20)-1)>>20, 10) + "Mi"
+}
+
+// binarySuffixes are the units used by FormatBytes, largest first.
+var binarySuffixes = []struct {
+	suffix string
+	shift  uint
+}{
+	{"Ti", 40}, {"Gi", 30}, {"Mi", 20},
+}
+
+// FormatBytes formats bytes as a quantity in the largest of Ti, Gi and Mi that
+// represents it exactly, or in Mi rounding up.
+func FormatBytes(bytes int64) string {
+	for _, s := range binarySuffixes {
+		if bytes != 0 && bytes%(1<<s.shift) == 0 {
+			return strconv.FormatInt(bytes>>s.shift, 10) + s.suffix
+		}
+	}
+	return FormatMebibytes(bytes)
+}
@@ -0,0 +1,99 @@
+// Package resource gives access to the resources of the FogAtlas API by type,
+// through the registry of the resource types.
+package resource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fogatlas/client-go/client/operations"
+	"github.com/fogatlas/client-go/models"
+	"github.com/go-openapi/runtime"
+)
+
+// NewModel returns a pointer to an empty model of the given resource type, or
+// nil if the resource type is unknown.
+func NewModel(resource string) interface{} {
+	rt, err := Lookup(resource)
+	if err != nil {
+		return nil
+	}
+	return rt.NewModel()
+}
+
+// List retrieves all the resources of the given type matching the filters and
+// returns their models. Empty filters are ignored.
+func List(ctx context.Context, client *operations.Client, resource string, filters map[string]string) ([]interface{}, error) {
+	rt, err := Lookup(resource)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range filters {
+		supported := false
+		for _, f := range rt.Filters {
+			supported = supported || f == name
+		}
+		if value != "" && !supported {
+			return nil, fmt.Errorf("filter %s is not valid for %s", name, rt.Name)
+		}
+	}
+	return rt.List(ctx, client, func(name string) *string {
+		if value := filters[name]; value != "" {
+			return &value
+		}
+		return nil
+	})
+}
+
+// Get retrieves a single resource of the given type and returns its model.
+func Get(ctx context.Context, client *operations.Client, resource string, id string) (interface{}, error) {
+	rt, err := Lookup(resource)
+	if err != nil {
+		return nil, err
+	}
+	return rt.Get(ctx, client, id)
+}
+
+// Put creates or updates a single resource of the given type. obj must be a
+// pointer to the model matching the resource type. It returns the message of
+// the API.
+func Put(ctx context.Context, client *operations.Client, resource string, id string, obj interface{}) (string, error) {
+	rt, err := Lookup(resource)
+	if err != nil {
+		return "", err
+	}
+	return rt.Put(ctx, client, id, obj)
+}
+
+// Delete deletes a single resource of the given type. It returns the message
+// of the API.
+func Delete(ctx context.Context, client *operations.Client, resource string, id string) (string, error) {
+	rt, err := Lookup(resource)
+	if err != nil {
+		return "", err
+	}
+	return rt.Delete(ctx, client, id)
+}
+
+// PatchDeploymentStatus changes the status of a deployment.
+func PatchDeploymentStatus(ctx context.Context, client *operations.Client, name string, status string) (string, error) {
+	params := operations.NewPatchDeploymentsNameParamsWithContext(ctx)
+	params.Name = name
+	params.PatchStatus = &models.PatchStatus{Status: status}
+	resp, err := client.PatchDeploymentsName(params)
+	if err != nil {
+		return "", err
+	}
+	return resp.Error(), nil
+}
+
+// IsNotFound reports whether err is the API answering that the resource does
+// not exist.
+func IsNotFound(err error) bool {
+	if apiErr, ok := err.(*runtime.APIError); ok {
+		return apiErr.Code == http.StatusNotFound
+	}
+	return strings.Contains(err.Error(), "[404]")
+}
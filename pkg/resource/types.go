@@ -0,0 +1,627 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fogatlas/client-go/client/operations"
+	"github.com/fogatlas/client-go/models"
+)
+
+// Type describes a type of resource of the FogAtlas API. Every command of
+// fogatlasctl handles the resource types through this descriptor, so a new
+// type only has to be added to Types.
+type Type struct {
+	// Name is the plural name used by the API and in the resource files
+	Name string
+	// Aliases are the other names accepted on the command line
+	Aliases []string
+	// NewModel returns a pointer to an empty model of the type
+	NewModel func() interface{}
+	// Kind is the kind of the kind-tagged manifests, e.g. Region
+	Kind string
+	// FileKey is the key of the type in the grouped resource files, Name if
+	// empty
+	FileKey string
+	// IDField is the json field identifying the resources, id if empty (see
+	// ID)
+	IDField string
+	// Filters are the filters accepted by List, e.g. region_id
+	Filters []string
+	// List retrieves the resources of the type; filter returns the value of a
+	// filter, nil if not set
+	List func(ctx context.Context, client *operations.Client, filter func(name string) *string) ([]interface{}, error)
+	// Get retrieves a single resource
+	Get func(ctx context.Context, client *operations.Client, id string) (interface{}, error)
+	// Put creates or updates a resource, obj is a pointer to the model
+	Put func(ctx context.Context, client *operations.Client, id string, obj interface{}) (string, error)
+	// Delete deletes a resource
+	Delete func(ctx context.Context, client *operations.Client, id string) (string, error)
+	// Tables are the tables printed by get
+	Tables []Table
+}
+
+// Table is a table printed by get, made of one or more rows for each resource.
+type Table struct {
+	Title  string
+	Header []string
+	Rows   func(obj interface{}) [][]string
+}
+
+// Types is the registry of the resource types, in the order putAll loads
+// them.
+var Types = []*Type{
+	{
+		Name:     "applications",
+		Aliases:  []string{"application", "app", "apps"},
+		NewModel: func() interface{} { return &models.Application{} },
+		Kind:     "Application",
+		List: func(ctx context.Context, client *operations.Client, filter func(string) *string) ([]interface{}, error) {
+			resp, err := client.GetApplications(operations.NewGetApplicationsParamsWithContext(ctx))
+			if err != nil {
+				return nil, err
+			}
+			var objs []interface{}
+			for _, obj := range resp.Payload.Applications {
+				objs = append(objs, obj)
+			}
+			return objs, nil
+		},
+		Get: func(ctx context.Context, client *operations.Client, id string) (interface{}, error) {
+			params := operations.NewGetApplicationsIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.GetApplicationsID(params)
+			if err != nil {
+				return nil, err
+			}
+			return resp.Payload, nil
+		},
+		Put: func(ctx context.Context, client *operations.Client, id string, obj interface{}) (string, error) {
+			params := operations.NewPutApplicationsIDParamsWithContext(ctx)
+			params.ID = id
+			params.Application = obj.(*models.Application)
+			resp, err := client.PutApplicationsID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Delete: func(ctx context.Context, client *operations.Client, id string) (string, error) {
+			params := operations.NewDeleteApplicationsIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.DeleteApplicationsID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Tables: []Table{{
+			Header: []string{"ID", "Name", "Description", "Status", "Microservice Id"},
+			Rows: func(obj interface{}) [][]string {
+				app := obj.(*models.Application)
+				var msids []string
+				for _, ms := range app.Microservices {
+					msids = append(msids, ms.MicroserviceID)
+				}
+				return [][]string{{app.ID, app.Name, app.Description, app.Status, strings.Join(msids, ",")}}
+			},
+		}},
+	},
+	{
+		Name:     "regions",
+		Aliases:  []string{"region", "reg", "regs"},
+		NewModel: func() interface{} { return &models.Region{} },
+		Kind:     "Region",
+		List: func(ctx context.Context, client *operations.Client, filter func(string) *string) ([]interface{}, error) {
+			resp, err := client.GetRegions(operations.NewGetRegionsParamsWithContext(ctx))
+			if err != nil {
+				return nil, err
+			}
+			var objs []interface{}
+			for _, obj := range resp.Payload.Regions {
+				objs = append(objs, obj)
+			}
+			return objs, nil
+		},
+		Get: func(ctx context.Context, client *operations.Client, id string) (interface{}, error) {
+			params := operations.NewGetRegionsIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.GetRegionsID(params)
+			if err != nil {
+				return nil, err
+			}
+			return resp.Payload, nil
+		},
+		Put: func(ctx context.Context, client *operations.Client, id string, obj interface{}) (string, error) {
+			params := operations.NewPutRegionsIDParamsWithContext(ctx)
+			params.ID = id
+			params.Region = obj.(*models.Region)
+			resp, err := client.PutRegionsID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Delete: func(ctx context.Context, client *operations.Client, id string) (string, error) {
+			params := operations.NewDeleteRegionsIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.DeleteRegionsID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Tables: []Table{{
+			Header: []string{"ID", "Description", "Location", "Tier", "CPUPrice", "MemPrice", "DiskPrice", "Relationship Id"},
+			Rows: func(obj interface{}) [][]string {
+				reg := obj.(*models.Region)
+				var relids []string
+				for _, rel := range reg.Relationships {
+					relids = append(relids, rel.RelationshipID)
+				}
+				var cpuPrice, memPrice, diskPrice string
+				if reg.Prices != nil {
+					cpuPrice = formatPrice(reg.Prices.CPU.MinPrice, reg.Prices.CPU.MaxPrice, reg.Prices.CPU.Scarcity, reg.Prices.CPU.UnitPrice)
+					memPrice = formatPrice(reg.Prices.Memory.MinPrice, reg.Prices.Memory.MaxPrice, reg.Prices.Memory.Scarcity, reg.Prices.Memory.UnitPrice)
+					diskPrice = formatPrice(reg.Prices.Disk.MinPrice, reg.Prices.Disk.MaxPrice, reg.Prices.Disk.Scarcity, reg.Prices.Disk.UnitPrice)
+				}
+				return [][]string{{reg.ID, reg.Description, reg.Location, strconv.FormatInt(reg.Tier, 10), cpuPrice, memPrice, diskPrice, strings.Join(relids, ",")}}
+			},
+		}},
+	},
+	{
+		Name:     "deployments",
+		Aliases:  []string{"deployment", "depl", "depls"},
+		NewModel: func() interface{} { return &models.Deployment{} },
+		Kind:     "Deployment",
+		IDField:  "name",
+		Filters:  []string{"status"},
+		List: func(ctx context.Context, client *operations.Client, filter func(string) *string) ([]interface{}, error) {
+			params := operations.NewGetDeploymentsParamsWithContext(ctx)
+			params.Status = filter("status")
+			resp, err := client.GetDeployments(params)
+			if err != nil {
+				return nil, err
+			}
+			var objs []interface{}
+			for _, obj := range resp.Payload.Deployments {
+				objs = append(objs, obj)
+			}
+			return objs, nil
+		},
+		Get: func(ctx context.Context, client *operations.Client, id string) (interface{}, error) {
+			params := operations.NewGetDeploymentsNameParamsWithContext(ctx)
+			params.Name = id
+			resp, err := client.GetDeploymentsName(params)
+			if err != nil {
+				return nil, err
+			}
+			return resp.Payload, nil
+		},
+		Put: func(ctx context.Context, client *operations.Client, id string, obj interface{}) (string, error) {
+			params := operations.NewPutDeploymentsNameParamsWithContext(ctx)
+			params.Name = id
+			params.Deployment = obj.(*models.Deployment)
+			resp, err := client.PutDeploymentsName(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Delete: func(ctx context.Context, client *operations.Client, id string) (string, error) {
+			params := operations.NewDeleteDeploymentsNameParamsWithContext(ctx)
+			params.Name = id
+			resp, err := client.DeleteDeploymentsName(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Tables: []Table{
+			{
+				Header: []string{"Name", "Description", "Status", "ExternalEndpointID"},
+				Rows: func(obj interface{}) [][]string {
+					depl := obj.(*models.Deployment)
+					return [][]string{{depl.Name, depl.Description, depl.Status, depl.ExternalendpointID}}
+				},
+			},
+			{
+				Title: "Microservices Requirements",
+				Header: []string{"Depl. Name", "Name", "Description", "CPURequired", "MemoryRequired", "DiskRequired",
+					"RegionID", "RegionRequired", "PriceRequired", "PriceComputed", "Deployment Descriptor"},
+				Rows: func(obj interface{}) [][]string {
+					depl := obj.(*models.Deployment)
+					var rows [][]string
+					for _, ms := range depl.Microservices {
+						rows = append(rows, []string{depl.Name, ms.Name, ms.Description, ms.CPURequired, ms.MemoryRequired, ms.DiskRequired,
+							ms.RegionID, ms.RegionRequired, strconv.FormatFloat(ms.PriceRequired, 'f', 2, 64),
+							strconv.FormatFloat(ms.PriceComputed, 'f', 2, 64), ms.DeploymentDescriptor})
+					}
+					return rows
+				},
+			},
+			{
+				Title:  "Dataflows",
+				Header: []string{"Depl. Name", "SourceID", "DestinationID", "BandwidthRequired", "LatencyRequired"},
+				Rows: func(obj interface{}) [][]string {
+					depl := obj.(*models.Deployment)
+					var rows [][]string
+					for _, df := range depl.Dataflows {
+						rows = append(rows, []string{depl.Name, df.SourceID, df.DestinationID, strconv.FormatInt(df.BandwidthRequired, 10),
+							strconv.FormatInt(df.LatencyRequired, 10)})
+					}
+					return rows
+				},
+			},
+		},
+	},
+	{
+		Name:     "microservices",
+		Aliases:  []string{"microservice", "ms"},
+		NewModel: func() interface{} { return &models.Microservice{} },
+		Kind:     "Microservice",
+		IDField:  "name",
+		Filters:  []string{"node_id"},
+		List: func(ctx context.Context, client *operations.Client, filter func(string) *string) ([]interface{}, error) {
+			params := operations.NewGetMicroservicesParamsWithContext(ctx)
+			params.NodeID = filter("node_id")
+			resp, err := client.GetMicroservices(params)
+			if err != nil {
+				return nil, err
+			}
+			var objs []interface{}
+			for _, obj := range resp.Payload.Microservices {
+				objs = append(objs, obj)
+			}
+			return objs, nil
+		},
+		Get: func(ctx context.Context, client *operations.Client, id string) (interface{}, error) {
+			params := operations.NewGetMicroservicesIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.GetMicroservicesID(params)
+			if err != nil {
+				return nil, err
+			}
+			return resp.Payload, nil
+		},
+		Put: func(ctx context.Context, client *operations.Client, id string, obj interface{}) (string, error) {
+			params := operations.NewPutMicroservicesIDParamsWithContext(ctx)
+			params.ID = id
+			params.Microservice = obj.(*models.Microservice)
+			resp, err := client.PutMicroservicesID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Delete: func(ctx context.Context, client *operations.Client, id string) (string, error) {
+			params := operations.NewDeleteMicroservicesIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.DeleteMicroservicesID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Tables: []Table{{
+			Header: []string{"ID", "Name", "Description", "ApplicationID", "NodeID", "RegionID", "Status"},
+			Rows: func(obj interface{}) [][]string {
+				ms := obj.(*models.Microservice)
+				return [][]string{{ms.ID, ms.Name, ms.Description, ms.ApplicationID, ms.NodeID, ms.RegionID, ms.Status}}
+			},
+		}},
+	},
+	{
+		Name:     "nodes",
+		Aliases:  []string{"node"},
+		NewModel: func() interface{} { return &models.Node{} },
+		Kind:     "Node",
+		Filters:  []string{"region_id"},
+		List: func(ctx context.Context, client *operations.Client, filter func(string) *string) ([]interface{}, error) {
+			params := operations.NewGetNodesParamsWithContext(ctx)
+			params.RegionID = filter("region_id")
+			resp, err := client.GetNodes(params)
+			if err != nil {
+				return nil, err
+			}
+			var objs []interface{}
+			for _, obj := range resp.Payload.Nodes {
+				objs = append(objs, obj)
+			}
+			return objs, nil
+		},
+		Get: func(ctx context.Context, client *operations.Client, id string) (interface{}, error) {
+			params := operations.NewGetNodesIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.GetNodesID(params)
+			if err != nil {
+				return nil, err
+			}
+			return resp.Payload, nil
+		},
+		Put: func(ctx context.Context, client *operations.Client, id string, obj interface{}) (string, error) {
+			params := operations.NewPutNodesIDParamsWithContext(ctx)
+			params.ID = id
+			params.Node = obj.(*models.Node)
+			resp, err := client.PutNodesID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Delete: func(ctx context.Context, client *operations.Client, id string) (string, error) {
+			params := operations.NewDeleteNodesIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.DeleteNodesID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Tables: []Table{{
+			Header: []string{"ID", "Architecture", "Version", "Distribution", "RegionID", "CPUCapacity", "CPUAvailable",
+				"MemoryCapacity", "MemoryAvailable", "DiskCapacity", "DiskAvailable", "Status"},
+			Rows: func(obj interface{}) [][]string {
+				node := obj.(*models.Node)
+				return [][]string{{node.ID, node.Architecture, node.Version, node.Distribution, node.RegionID, node.CPUCapacity, node.CPUAvailable,
+					node.MemoryCapacity, node.MemoryAvailable, node.DiskCapacity, node.DiskAvailable, node.Status}}
+			},
+		}},
+	},
+	{
+		Name:     "relationships",
+		Aliases:  []string{"relationship", "rel", "rels"},
+		NewModel: func() interface{} { return &models.Relationship{} },
+		Kind:     "Relationship",
+		Filters:  []string{"region_id"},
+		List: func(ctx context.Context, client *operations.Client, filter func(string) *string) ([]interface{}, error) {
+			params := operations.NewGetRelationshipsParamsWithContext(ctx)
+			params.RegionID = filter("region_id")
+			resp, err := client.GetRelationships(params)
+			if err != nil {
+				return nil, err
+			}
+			var objs []interface{}
+			for _, obj := range resp.Payload.Relationships {
+				objs = append(objs, obj)
+			}
+			return objs, nil
+		},
+		Get: func(ctx context.Context, client *operations.Client, id string) (interface{}, error) {
+			params := operations.NewGetRelationshipsIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.GetRelationshipsID(params)
+			if err != nil {
+				return nil, err
+			}
+			return resp.Payload, nil
+		},
+		Put: func(ctx context.Context, client *operations.Client, id string, obj interface{}) (string, error) {
+			params := operations.NewPutRelationshipsIDParamsWithContext(ctx)
+			params.ID = id
+			params.Relationship = obj.(*models.Relationship)
+			resp, err := client.PutRelationshipsID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Delete: func(ctx context.Context, client *operations.Client, id string) (string, error) {
+			params := operations.NewDeleteRelationshipsIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.DeleteRelationshipsID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Tables: []Table{{
+			Header: []string{"ID", "EndpointA", "EndpointB", "RegionID", "BandwidthCapacity", "BandwidthAvailable",
+				"Latency", "BandwidthPrice", "LatencyPrice", "Status"},
+			Rows: func(obj interface{}) [][]string {
+				rel := obj.(*models.Relationship)
+				var bwPrice, latPrice string
+				if rel.Prices != nil {
+					bwPrice = formatPrice(rel.Prices.Bandwidth.MinPrice, rel.Prices.Bandwidth.MaxPrice, rel.Prices.Bandwidth.Scarcity, rel.Prices.Bandwidth.UnitPrice)
+					latPrice = formatPrice(rel.Prices.Latency.MinPrice, rel.Prices.Latency.MaxPrice, rel.Prices.Latency.Scarcity, rel.Prices.Latency.UnitPrice)
+				}
+				return [][]string{{rel.ID, rel.EndpointA, rel.EndpointB, rel.RegionID, strconv.FormatInt(rel.BandwidthCapacity, 10),
+					strconv.FormatInt(rel.BandwidthAvailable, 10), strconv.FormatInt(rel.Latency, 10), bwPrice, latPrice, rel.Status}}
+			},
+		}},
+	},
+	{
+		Name: "externalendpoints",
+		// "external endpoints" was the name expected by delete
+		Aliases:  []string{"externalendpoint", "ee", "ees", "external endpoints"},
+		NewModel: func() interface{} { return &models.ExternalEndpoint{} },
+		Kind:     "ExternalEndpoint",
+		Filters:  []string{"region_id"},
+		List: func(ctx context.Context, client *operations.Client, filter func(string) *string) ([]interface{}, error) {
+			params := operations.NewGetExternalendpointsParamsWithContext(ctx)
+			params.RegionID = filter("region_id")
+			resp, err := client.GetExternalendpoints(params)
+			if err != nil {
+				return nil, err
+			}
+			var objs []interface{}
+			for _, obj := range resp.Payload.Externalendpoints {
+				objs = append(objs, obj)
+			}
+			return objs, nil
+		},
+		Get: func(ctx context.Context, client *operations.Client, id string) (interface{}, error) {
+			params := operations.NewGetExternalendpointsIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.GetExternalendpointsID(params)
+			if err != nil {
+				return nil, err
+			}
+			return resp.Payload, nil
+		},
+		Put: func(ctx context.Context, client *operations.Client, id string, obj interface{}) (string, error) {
+			params := operations.NewPutExternalendpointsIDParamsWithContext(ctx)
+			params.ID = id
+			params.Externalendpoint = obj.(*models.ExternalEndpoint)
+			resp, err := client.PutExternalendpointsID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Delete: func(ctx context.Context, client *operations.Client, id string) (string, error) {
+			params := operations.NewDeleteExternalendpointsIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.DeleteExternalendpointsID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Tables: []Table{{
+			Header: []string{"ID", "Name", "Description", "Type", "Location", "RegionID", "IPAddress"},
+			Rows: func(obj interface{}) [][]string {
+				th := obj.(*models.ExternalEndpoint)
+				return [][]string{{th.ID, th.Name, th.Description, th.Type, th.Location, th.RegionID, th.IPAddress}}
+			},
+		}},
+	},
+	{
+		Name:     "dynamicnodes",
+		Aliases:  []string{"dynamicnode", "dn", "dns"},
+		NewModel: func() interface{} { return &models.DynamicNode{} },
+		Kind:     "DynamicNode",
+		// the grouped files have always used the singular
+		FileKey: "dynamicnode",
+		Filters: []string{"region_id"},
+		List: func(ctx context.Context, client *operations.Client, filter func(string) *string) ([]interface{}, error) {
+			params := operations.NewGetDynamicnodesParamsWithContext(ctx)
+			params.RegionID = filter("region_id")
+			resp, err := client.GetDynamicnodes(params)
+			if err != nil {
+				return nil, err
+			}
+			var objs []interface{}
+			for _, obj := range resp.Payload.Dynamicnodes {
+				objs = append(objs, obj)
+			}
+			return objs, nil
+		},
+		Get: func(ctx context.Context, client *operations.Client, id string) (interface{}, error) {
+			params := operations.NewGetDynamicnodesIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.GetDynamicnodesID(params)
+			if err != nil {
+				return nil, err
+			}
+			return resp.Payload, nil
+		},
+		Put: func(ctx context.Context, client *operations.Client, id string, obj interface{}) (string, error) {
+			params := operations.NewPutDynamicnodesIDParamsWithContext(ctx)
+			params.ID = id
+			params.Dynamicnode = obj.(*models.DynamicNode)
+			resp, err := client.PutDynamicnodesID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Delete: func(ctx context.Context, client *operations.Client, id string) (string, error) {
+			params := operations.NewDeleteDynamicnodesIDParamsWithContext(ctx)
+			params.ID = id
+			resp, err := client.DeleteDynamicnodesID(params)
+			if err != nil {
+				return "", err
+			}
+			return resp.Error(), nil
+		},
+		Tables: []Table{{
+			Header: []string{"ID", "IPAddress", "NodeID", "RegionID"},
+			Rows: func(obj interface{}) [][]string {
+				dyn := obj.(*models.DynamicNode)
+				return [][]string{{dyn.ID, dyn.IPAddress, dyn.NodeID, dyn.RegionID}}
+			},
+		}},
+	},
+}
+
+// formatPrice formats the parameters of a price as min,max,scarcity,unit.
+func formatPrice(min, max, scarcity, unit float64) string {
+	var values []string
+	for _, v := range []float64{min, max, scarcity, unit} {
+		values = append(values, strconv.FormatFloat(v, 'f', 2, 64))
+	}
+	return strings.Join(values, ",")
+}
+
+// Lookup returns the resource type with the given name or alias.
+func Lookup(name string) (*Type, error) {
+	for _, rt := range Types {
+		if rt.Name == name {
+			return rt, nil
+		}
+		for _, alias := range rt.Aliases {
+			if alias == name {
+				return rt, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("resource specified (%s) is unknown", name)
+}
+
+// LookupKind returns the resource type of a kind of manifest, nil if unknown.
+func LookupKind(kind string) *Type {
+	for _, rt := range Types {
+		if rt.Kind == kind {
+			return rt
+		}
+	}
+	return nil
+}
+
+// LookupFileKey returns the resource type of a key of the grouped resource
+// files, nil if unknown.
+func LookupFileKey(key string) *Type {
+	for _, rt := range Types {
+		if rt.Key() == key {
+			return rt
+		}
+	}
+	return nil
+}
+
+// Key returns the key of the type in the grouped resource files.
+func (t *Type) Key() string {
+	if t.FileKey != "" {
+		return t.FileKey
+	}
+	return t.Name
+}
+
+// IDKey returns the json field identifying the resources of the type, in the
+// resource files as in the requests.
+func (t *Type) IDKey() string {
+	if t.IDField != "" {
+		return t.IDField
+	}
+	return "id"
+}
+
+// ID returns the identifier of a resource of the type, that is the value of
+// the IDKey field of its json document. Every command identifies the
+// resources through it.
+func (t *Type) ID(obj interface{}) string {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return ""
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return ""
+	}
+	id, _ := doc[t.IDKey()].(string)
+	return id
+}
@@ -0,0 +1,179 @@
+// Package selector selects resources by the value of their fields, with the
+// syntax of the Kubernetes label and field selectors.
+package selector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operators of a requirement.
+const (
+	Equals       = "="
+	NotEquals    = "!="
+	In           = "in"
+	NotIn        = "notin"
+	Exists       = "exists"
+	DoesNotExist = "!"
+)
+
+// Requirement is a condition on a field of a resource.
+type Requirement struct {
+	// Key is the path of the field, e.g. prices.cpu.unit_price
+	Key      string
+	Operator string
+	Values   []string
+}
+
+// Selector is a set of requirements that must all be met.
+type Selector []Requirement
+
+// Fields returns the values of a field of a resource given its path, nil if
+// the field is not set. A field has several values when its path goes
+// through a list.
+type Fields func(path string) []string
+
+// Parse parses a selector in the syntax of the Kubernetes label selectors,
+// e.g. "status=up,architecture in (arm64,aarch64),!deleted". The supported
+// requirements are key=value, key==value, key!=value, key in (v1,v2),
+// key notin (v1,v2), key and !key.
+func Parse(expr string) (Selector, error) {
+	var sel Selector
+	for _, term := range splitTerms(expr) {
+		req, err := parseTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, req)
+	}
+	return sel, nil
+}
+
+// ParseFields parses a selector in the syntax of the Kubernetes field
+// selectors, e.g. "status=up,tier!=0". The supported requirements are
+// key=value, key==value and key!=value.
+func ParseFields(expr string) (Selector, error) {
+	var sel Selector
+	for _, term := range splitTerms(expr) {
+		req, err := parseTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		if req.Operator != Equals && req.Operator != NotEquals {
+			return nil, fmt.Errorf("wrong field selector %q, expected key=value or key!=value", term)
+		}
+		sel = append(sel, req)
+	}
+	return sel, nil
+}
+
+// splitTerms splits a selector on the commas that are not in parentheses.
+func splitTerms(expr string) []string {
+	var terms []string
+	depth := 0
+	start := 0
+	for i, r := range expr {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	terms = append(terms, expr[start:])
+	var nonEmpty []string
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			nonEmpty = append(nonEmpty, term)
+		}
+	}
+	return nonEmpty
+}
+
+// parseTerm parses a single requirement.
+func parseTerm(term string) (Requirement, error) {
+	if i := strings.Index(term, "!="); i >= 0 {
+		return newRequirement(term, term[:i], NotEquals, term[i+2:])
+	}
+	if i := strings.Index(term, "=="); i >= 0 {
+		return newRequirement(term, term[:i], Equals, term[i+2:])
+	}
+	if i := strings.Index(term, "="); i >= 0 {
+		return newRequirement(term, term[:i], Equals, term[i+1:])
+	}
+	fields := strings.Fields(term)
+	if len(fields) >= 2 && (fields[1] == In || fields[1] == NotIn) {
+		set := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(term[len(fields[0]):]), fields[1]))
+		if !strings.HasPrefix(set, "(") || !strings.HasSuffix(set, ")") {
+			return Requirement{}, fmt.Errorf("wrong selector %q, expected %s (value,...)", term, fields[1])
+		}
+		var values []string
+		for _, v := range strings.Split(set[1:len(set)-1], ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			return Requirement{}, fmt.Errorf("wrong selector %q, the set of values is empty", term)
+		}
+		return Requirement{Key: fields[0], Operator: fields[1], Values: values}, nil
+	}
+	if len(fields) != 1 {
+		return Requirement{}, fmt.Errorf("wrong selector %q", term)
+	}
+	if strings.HasPrefix(term, "!") {
+		return newRequirement(term, term[1:], DoesNotExist, "")
+	}
+	return newRequirement(term, term, Exists, "")
+}
+
+// newRequirement checks the key of a requirement with at most one value.
+func newRequirement(term string, key string, op string, value string) (Requirement, error) {
+	key = strings.TrimPrefix(strings.TrimSpace(key), ".")
+	if key == "" || strings.ContainsAny(key, " \t()!=") {
+		return Requirement{}, fmt.Errorf("wrong selector %q, the key is missing or not valid", term)
+	}
+	req := Requirement{Key: key, Operator: op}
+	if op == Equals || op == NotEquals {
+		req.Values = []string{strings.TrimSpace(value)}
+	}
+	return req, nil
+}
+
+// Matches reports whether a resource, whose fields are given by fields, meets
+// all the requirements of the selector. As in Kubernetes, != and notin match
+// the resources where the field is not set.
+func (sel Selector) Matches(fields Fields) bool {
+	for _, req := range sel {
+		if !req.matches(fields(req.Key)) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether the values of a field meet the requirement.
+func (req Requirement) matches(values []string) bool {
+	found := false
+	for _, v := range values {
+		for _, want := range req.Values {
+			found = found || v == want
+		}
+	}
+	switch req.Operator {
+	case Equals, In:
+		return found
+	case NotEquals, NotIn:
+		return !found
+	case Exists:
+		return len(values) > 0
+	case DoesNotExist:
+		return len(values) == 0
+	}
+	return false
+}
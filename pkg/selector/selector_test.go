@@ -0,0 +1,119 @@
+package selector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		want Selector
+		err  bool
+	}{
+		{"", nil, false},
+		{"status=up", Selector{{"status", Equals, []string{"up"}}}, false},
+		{"status==up", Selector{{"status", Equals, []string{"up"}}}, false},
+		{"status = up", Selector{{"status", Equals, []string{"up"}}}, false},
+		{"status=", Selector{{"status", Equals, []string{""}}}, false},
+		{"status!=down", Selector{{"status", NotEquals, []string{"down"}}}, false},
+		{"tier in (1,2)", Selector{{"tier", In, []string{"1", "2"}}}, false},
+		{"tier in ( 1 , 2 )", Selector{{"tier", In, []string{"1", "2"}}}, false},
+		{"tier notin (0)", Selector{{"tier", NotIn, []string{"0"}}}, false},
+		{"deleted", Selector{{"deleted", Exists, nil}}, false},
+		{"!deleted", Selector{{"deleted", DoesNotExist, nil}}, false},
+		{".prices.cpu.unit_price=3", Selector{{"prices.cpu.unit_price", Equals, []string{"3"}}}, false},
+		{"status=up,architecture in (arm64,aarch64),!deleted", Selector{
+			{"status", Equals, []string{"up"}},
+			{"architecture", In, []string{"arm64", "aarch64"}},
+			{"deleted", DoesNotExist, nil},
+		}, false},
+		{"status=up,,", Selector{{"status", Equals, []string{"up"}}}, false},
+		{"=up", nil, true},
+		{"!=up", nil, true},
+		{"!", nil, true},
+		{"tier in ()", nil, true},
+		{"tier in 1,2", nil, true},
+		{"tier notin (1", nil, true},
+		{"a b", nil, true},
+		{"!a=b", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			got, err := Parse(test.expr)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	for _, expr := range []string{"status=up", "status==up,tier!=0"} {
+		if _, err := ParseFields(expr); err != nil {
+			t.Errorf("%s: unexpected error: %s", expr, err)
+		}
+	}
+	for _, expr := range []string{"tier in (1)", "tier notin (1)", "deleted", "!deleted"} {
+		if _, err := ParseFields(expr); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	fields := Fields(func(path string) []string {
+		return map[string][]string{
+			"status":       {"up"},
+			"tier":         {"1"},
+			"microservice": {"a", "b"},
+		}[path]
+	})
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"status=up", true},
+		{"status=down", false},
+		{"status!=down", true},
+		{"status!=up", false},
+		{"missing!=up", true},
+		{"missing=up", false},
+		{"tier in (1,2)", true},
+		{"tier in (2,3)", false},
+		{"missing in (1)", false},
+		{"tier notin (2,3)", true},
+		{"tier notin (1)", false},
+		{"missing notin (1)", true},
+		{"status", true},
+		{"missing", false},
+		{"!missing", true},
+		{"!status", false},
+		{"microservice=b", true},
+		{"microservice!=b", false},
+		{"microservice notin (c)", true},
+		{"status=up,tier in (1),!missing", true},
+		{"status=up,tier in (2)", false},
+		{"", true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			sel, err := Parse(test.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := sel.Matches(fields); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package update
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/fogatlas/fogatlasctl/pkg/manifest"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/ghodss/yaml"
+)
+
+// Operation is an operation of a json patch (RFC 6902).
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// MergePatch returns a change applying a json merge patch (RFC 7386).
+func MergePatch(patch map[string]interface{}) Change {
+	return func(doc interface{}) (interface{}, error) {
+		m, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("a merge patch applies to an object")
+		}
+		return manifest.MergePatch(m, patch), nil
+	}
+}
+
+// JSONPatch returns a change applying a json patch (RFC 6902).
+func JSONPatch(ops []Operation) Change {
+	return func(doc interface{}) (interface{}, error) {
+		var err error
+		for i, op := range ops {
+			doc, err = applyOperation(doc, op)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d (%s %s): %s", i, op.Op, op.Path, err)
+			}
+		}
+		return doc, nil
+	}
+}
+
+func applyOperation(doc interface{}, op Operation) (interface{}, error) {
+	switch op.Op {
+	case "add":
+		return add(doc, op.Path, op.Value)
+	case "remove":
+		doc, _, err := remove(doc, op.Path)
+		return doc, err
+	case "replace":
+		if op.Path == "" {
+			// the whole document
+			return op.Value, nil
+		}
+		if _, err := get(doc, op.Path); err != nil {
+			return nil, err
+		}
+		doc, _, err := remove(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, op.Value)
+	case "move":
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%s cannot be moved inside itself", op.From)
+		}
+		doc, value, err := remove(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, value)
+	case "copy":
+		value, err := get(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, deepCopy(value))
+	case "test":
+		value, err := get(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, op.Value) {
+			return nil, fmt.Errorf("test failed, the value is %v", value)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("operation unknown")
+}
+
+// pointer splits a json pointer (RFC 6901) in its reference tokens.
+func pointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path %s does not start with /", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// index parses the index of a list of length n; "-" is n, past the end, when
+// end is true.
+func index(token string, n int, end bool) (int, error) {
+	if token == "-" && end {
+		return n, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > n || i == n && !end || token != strconv.Itoa(i) {
+		return 0, fmt.Errorf("index %s is out of range", token)
+	}
+	return i, nil
+}
+
+func get(doc interface{}, path string) (interface{}, error) {
+	tokens, err := pointer(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if doc, ok = v[t]; !ok {
+				return nil, fmt.Errorf("path %s does not exist", path)
+			}
+		case []interface{}:
+			i, err := index(t, len(v), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = v[i]
+		default:
+			return nil, fmt.Errorf("path %s does not exist", path)
+		}
+	}
+	return doc, nil
+}
+
+// replaceAt replaces the value at the end of path with the result of f, which
+// receives the container and the last token. It returns the new document.
+func replaceAt(doc interface{}, tokens []string, f func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return f(doc, tokens[0])
+	}
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		child, ok := v[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("%s does not exist", tokens[0])
+		}
+		child, err := replaceAt(child, tokens[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		v[tokens[0]] = child
+		return v, nil
+	case []interface{}:
+		i, err := index(tokens[0], len(v), false)
+		if err != nil {
+			return nil, err
+		}
+		child, err := replaceAt(v[i], tokens[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = child
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s is neither an object nor a list", tokens[0])
+}
+
+func add(doc interface{}, path string, value interface{}) (interface{}, error) {
+	tokens, err := pointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return replaceAt(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch v := parent.(type) {
+		case map[string]interface{}:
+			v[token] = value
+			return v, nil
+		case []interface{}:
+			i, err := index(token, len(v), true)
+			if err != nil {
+				return nil, err
+			}
+			v = append(v, nil)
+			copy(v[i+1:], v[i:])
+			v[i] = value
+			return v, nil
+		}
+		return nil, fmt.Errorf("path %s does not exist", path)
+	})
+}
+
+func remove(doc interface{}, path string) (interface{}, interface{}, error) {
+	tokens, err := pointer(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil, fmt.Errorf("the whole document cannot be removed")
+	}
+	var removed interface{}
+	doc, err = replaceAt(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch v := parent.(type) {
+		case map[string]interface{}:
+			value, ok := v[token]
+			if !ok {
+				return nil, fmt.Errorf("path %s does not exist", path)
+			}
+			removed = value
+			delete(v, token)
+			return v, nil
+		case []interface{}:
+			i, err := index(token, len(v), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = v[i]
+			return append(v[:i], v[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("path %s does not exist", path)
+	})
+	return doc, removed, err
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			m[key] = deepCopy(child)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, child := range v {
+			l[i] = deepCopy(child)
+		}
+		return l
+	}
+	return value
+}
+
+// Set returns a change setting fields of a model of the given type, given as
+// path=value where path is the json name of the field, nested with dots
+// (e.g. prices.cpu.unit_price=3), a number selecting an element of a list.
+// The value of a string field is taken as is, the other values are parsed as
+// yaml, so that numbers, booleans and lists can be written. null removes the
+// field.
+func Set(res string, assignments []string) (Change, error) {
+	model := resource.NewModel(res)
+	if model == nil {
+		return nil, fmt.Errorf("resource specified (%s) is unknown", res)
+	}
+	type assignment struct {
+		keys  []string
+		value interface{}
+	}
+	var fields []assignment
+	for _, a := range assignments {
+		i := strings.Index(a, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("%s is not of the form field=value", a)
+		}
+		path, raw := a[:i], a[i+1:]
+		t := resource.FieldType(model, path)
+		if t == nil {
+			return nil, fmt.Errorf("%s have no field %s", res, path)
+		}
+		var value interface{} = raw
+		if raw == "null" {
+			value = nil
+		} else if t.Kind() != reflect.String {
+			if err := yaml.Unmarshal([]byte(raw), &value); err != nil {
+				return nil, fmt.Errorf("value of %s (%s) is not valid: %s", path, raw, err)
+			}
+		}
+		fields = append(fields, assignment{strings.Split(path, "."), value})
+	}
+	return func(doc interface{}) (interface{}, error) {
+		for _, f := range fields {
+			var err error
+			doc, err = setPath(doc, f.keys, f.value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", strings.Join(f.keys, "."), err)
+			}
+		}
+		return doc, nil
+	}, nil
+}
+
+// setPath sets the value at keys, creating the missing objects. A nil value
+// removes the field.
+func setPath(doc interface{}, keys []string, value interface{}) (interface{}, error) {
+	if len(keys) == 0 {
+		return value, nil
+	}
+	switch v := doc.(type) {
+	case nil:
+		if value == nil {
+			return nil, nil
+		}
+		child, err := setPath(nil, keys[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{keys[0]: child}, nil
+	case map[string]interface{}:
+		if len(keys) == 1 && value == nil {
+			delete(v, keys[0])
+			return v, nil
+		}
+		child, err := setPath(v[keys[0]], keys[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		v[keys[0]] = child
+		return v, nil
+	case []interface{}:
+		i, err := index(keys[0], len(v), false)
+		if err != nil {
+			return nil, err
+		}
+		child, err := setPath(v[i], keys[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = child
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s is neither an object nor a list", keys[0])
+}
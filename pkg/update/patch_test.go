@@ -0,0 +1,182 @@
+package update
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// decode returns the document of a json string.
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		t.Fatalf("wrong json %s: %s", s, err)
+	}
+	return doc
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		ops  string
+		want string
+		err  bool
+	}{
+		{"add field", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`, false},
+		{"add replaces field", `{"a":1}`, `[{"op":"add","path":"/a","value":2}]`, `{"a":2}`, false},
+		{"add nested", `{"a":{"b":1}}`, `[{"op":"add","path":"/a/c","value":2}]`, `{"a":{"b":1,"c":2}}`, false},
+		{"add missing parent", `{"a":1}`, `[{"op":"add","path":"/b/c","value":2}]`, ``, true},
+		{"add array index", `{"l":[1,3]}`, `[{"op":"add","path":"/l/1","value":2}]`, `{"l":[1,2,3]}`, false},
+		{"add array end index", `{"l":[1,2]}`, `[{"op":"add","path":"/l/2","value":3}]`, `{"l":[1,2,3]}`, false},
+		{"add array dash", `{"l":[1,2]}`, `[{"op":"add","path":"/l/-","value":3}]`, `{"l":[1,2,3]}`, false},
+		{"add array out of range", `{"l":[1,2]}`, `[{"op":"add","path":"/l/3","value":3}]`, ``, true},
+		{"add array leading zero", `{"l":[1,2]}`, `[{"op":"add","path":"/l/01","value":3}]`, ``, true},
+		{"add whole document", `{"a":1}`, `[{"op":"add","path":"","value":{"b":2}}]`, `{"b":2}`, false},
+		{"add escaped key", `{}`, `[{"op":"add","path":"/a~1b~0c","value":1}]`, `{"a/b~c":1}`, false},
+		{"remove field", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`, false},
+		{"remove missing field", `{"a":1}`, `[{"op":"remove","path":"/b"}]`, ``, true},
+		{"remove array index", `{"l":[1,2,3]}`, `[{"op":"remove","path":"/l/1"}]`, `{"l":[1,3]}`, false},
+		{"remove array dash", `{"l":[1,2,3]}`, `[{"op":"remove","path":"/l/-"}]`, ``, true},
+		{"remove whole document", `{"a":1}`, `[{"op":"remove","path":""}]`, ``, true},
+		{"replace field", `{"a":1}`, `[{"op":"replace","path":"/a","value":"x"}]`, `{"a":"x"}`, false},
+		{"replace missing field", `{"a":1}`, `[{"op":"replace","path":"/b","value":2}]`, ``, true},
+		{"replace array index", `{"l":[1,2]}`, `[{"op":"replace","path":"/l/0","value":0}]`, `{"l":[0,2]}`, false},
+		{"replace whole document", `{"a":1}`, `[{"op":"replace","path":"","value":{"b":2}}]`, `{"b":2}`, false},
+		{"move field", `{"a":1,"b":{}}`, `[{"op":"move","from":"/a","path":"/b/c"}]`, `{"b":{"c":1}}`, false},
+		{"move array element", `{"l":[1,2,3]}`, `[{"op":"move","from":"/l/0","path":"/l/-"}]`, `{"l":[2,3,1]}`, false},
+		{"move inside itself", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, ``, true},
+		{"copy field", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":1},"c":{"b":1}}`, false},
+		{"copy is deep", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`, false},
+		{"copy missing field", `{"a":1}`, `[{"op":"copy","from":"/b","path":"/c"}]`, ``, true},
+		{"test succeeds", `{"a":{"b":[1,"x"]}}`, `[{"op":"test","path":"/a/b","value":[1,"x"]}]`, `{"a":{"b":[1,"x"]}}`, false},
+		{"test fails", `{"a":1}`, `[{"op":"test","path":"/a","value":2}]`, ``, true},
+		{"test stops the patch", `{"a":1}`, `[{"op":"test","path":"/a","value":2},{"op":"remove","path":"/a"}]`, ``, true},
+		{"path without slash", `{"a":1}`, `[{"op":"remove","path":"a"}]`, ``, true},
+		{"unknown operation", `{"a":1}`, `[{"op":"rename","path":"/a"}]`, ``, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ops []Operation
+			if err := json.Unmarshal([]byte(test.ops), &ops); err != nil {
+				t.Fatalf("wrong operations: %s", err)
+			}
+			got, err := JSONPatch(ops)(decode(t, test.doc))
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if want := decode(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"set field", `{"a":1}`, `{"b":2}`, `{"a":1,"b":2}`},
+		{"null deletes field", `{"a":1,"b":2}`, `{"a":null}`, `{"b":2}`},
+		{"null deletes nested field", `{"a":{"b":1,"c":2}}`, `{"a":{"b":null}}`, `{"a":{"c":2}}`},
+		{"null on missing field", `{"a":1}`, `{"b":null}`, `{"a":1}`},
+		{"object merged", `{"a":{"b":1}}`, `{"a":{"c":2}}`, `{"a":{"b":1,"c":2}}`},
+		{"object replaces value", `{"a":1}`, `{"a":{"b":2}}`, `{"a":{"b":2}}`},
+		{"array replaced", `{"l":[1,2,3]}`, `{"l":[4]}`, `{"l":[4]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch := decode(t, test.patch).(map[string]interface{})
+			got, err := MergePatch(patch)(decode(t, test.doc))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if want := decode(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+
+	if _, err := MergePatch(map[string]interface{}{})(decode(t, `[1]`)); err == nil {
+		t.Errorf("expected an error on a list")
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name        string
+		doc         string
+		assignments []string
+		want        string
+		err         bool
+	}{
+		{"string field", `{"id":"R1"}`, []string{"location=Trento"}, `{"id":"R1","location":"Trento"}`, false},
+		{"string kept as is", `{"id":"R1"}`, []string{"description=1"}, `{"id":"R1","description":"1"}`, false},
+		{"empty string", `{"id":"R1","location":"x"}`, []string{"location="}, `{"id":"R1","location":""}`, false},
+		{"value with equals", `{"id":"R1"}`, []string{"description=a=b"}, `{"id":"R1","description":"a=b"}`, false},
+		{"number parsed", `{"id":"R1"}`, []string{"tier=2"}, `{"id":"R1","tier":2}`, false},
+		{"nested objects created", `{"id":"R1"}`, []string{"prices.cpu.unit_price=3"}, `{"id":"R1","prices":{"cpu":{"unit_price":3}}}`, false},
+		{"nested field set", `{"prices":{"cpu":{"unit_price":1,"scarcity":2}}}`, []string{"prices.cpu.unit_price=3"}, `{"prices":{"cpu":{"unit_price":3,"scarcity":2}}}`, false},
+		{"list index", `{"relationships":[{"relationship_id":"a"},{"relationship_id":"b"}]}`, []string{"relationships.1.relationship_id=c"}, `{"relationships":[{"relationship_id":"a"},{"relationship_id":"c"}]}`, false},
+		{"list index out of range", `{"relationships":[{"relationship_id":"a"}]}`, []string{"relationships.1.relationship_id=c"}, ``, true},
+		{"list parsed", `{"id":"R1"}`, []string{"relationships=[{relationship_id: a}]"}, `{"id":"R1","relationships":[{"relationship_id":"a"}]}`, false},
+		{"null removes field", `{"id":"R1","tier":2}`, []string{"tier=null"}, `{"id":"R1"}`, false},
+		{"null on missing field", `{"id":"R1"}`, []string{"tier=null"}, `{"id":"R1"}`, false},
+		{"null nested", `{"prices":{"cpu":{"unit_price":1,"scarcity":2}}}`, []string{"prices.cpu.unit_price=null"}, `{"prices":{"cpu":{"scarcity":2}}}`, false},
+		{"several fields", `{"id":"R1"}`, []string{"tier=1", "location=Povo"}, `{"id":"R1","tier":1,"location":"Povo"}`, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			change, err := Set("regions", test.assignments)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			got, err := change(decode(t, test.doc))
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if want := decode(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSetParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		res         string
+		assignments []string
+	}{
+		{"unknown type", "things", []string{"id=1"}},
+		{"no equals", "regions", []string{"tier"}},
+		{"no field", "regions", []string{"=1"}},
+		{"unknown field", "regions", []string{"altitude=1"}},
+		{"unknown nested field", "regions", []string{"prices.gpu.unit_price=1"}},
+		{"field of a value", "regions", []string{"tier.x=1"}},
+		{"wrong number", "regions", []string{"tier=[1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Set(test.res, test.assignments); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Package update changes resources of the API field by field. The API only
+// replaces whole resources, so the changes are made on the client side: the
+// resource is retrieved, changed and put back, unless it was changed by
+// someone else in between.
+package update
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fogatlas/client-go/client/operations"
+	"github.com/fogatlas/fogatlasctl/pkg/diff"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/ghodss/yaml"
+)
+
+// Change changes the json document of a resource and returns the new one.
+type Change func(doc interface{}) (interface{}, error)
+
+// ConflictError is returned when a resource was changed by someone else
+// between the moment it was retrieved and the moment it was to be put back.
+type ConflictError struct {
+	Resource string
+	ID       string
+	// Diff shows the changes made in the meantime, see diff.Lines.
+	Diff string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %s was changed by someone else in the meantime, retrieve it again and retry:\n%s", e.Resource, e.ID, strings.TrimSuffix(e.Diff, "\n"))
+}
+
+// Fingerprint returns a digest of the json document of a model, empty for
+// nil, that is a resource that does not exist.
+func Fingerprint(obj interface{}) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Verify retrieves a resource again and fails with a *ConflictError when it
+// is no longer before, the model retrieved before it was changed (nil when it
+// did not exist).
+func Verify(ctx context.Context, client *operations.Client, res string, id string, before interface{}) error {
+	current, err := resource.Get(ctx, client, res, id)
+	if err != nil {
+		if !resource.IsNotFound(err) {
+			return fmt.Errorf("get %s %s failed: %s", res, id, err)
+		}
+		current = nil
+	}
+	expected, err := Fingerprint(before)
+	if err != nil {
+		return err
+	}
+	actual, err := Fingerprint(current)
+	if err != nil {
+		return err
+	}
+	if actual == expected {
+		return nil
+	}
+	return &ConflictError{Resource: res, ID: id, Diff: diff.Lines(toYAML(before), toYAML(current))}
+}
+
+// toYAML prints a model in yaml, an empty string for nil.
+func toYAML(obj interface{}) string {
+	if obj == nil {
+		return ""
+	}
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Sprint(obj)
+	}
+	return string(data)
+}
+
+// Result is the outcome of an update.
+type Result struct {
+	// Before and After are the models of the resource before and after the
+	// change.
+	Before  interface{}
+	After   interface{}
+	Changed bool
+	// Message is the message of the API, empty when nothing was put.
+	Message string
+}
+
+// Update retrieves a resource, applies change to its json document and puts
+// the result back if it differs. The identifier cannot be changed. Right
+// before the put, the resource is retrieved again and the update fails with
+// a *ConflictError if it is no longer the one that was changed, see Verify.
+func Update(ctx context.Context, client *operations.Client, res string, id string, change Change) (*Result, error) {
+	rt, err := resource.Lookup(res)
+	if err != nil {
+		return nil, err
+	}
+	before, err := rt.Get(ctx, client, id)
+	if err != nil {
+		return nil, fmt.Errorf("get %s %s failed: %s", rt.Name, id, err)
+	}
+	data, err := json.Marshal(before)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	doc, err = change(doc)
+	if err != nil {
+		return nil, err
+	}
+	after, err := Decode(rt, doc)
+	if err != nil {
+		return nil, err
+	}
+	if afterID := rt.ID(after); afterID != id {
+		return nil, fmt.Errorf("the identifier cannot be changed (%s instead of %s)", afterID, id)
+	}
+
+	result := &Result{Before: before, After: after}
+	x, err := Fingerprint(before)
+	if err != nil {
+		return nil, err
+	}
+	y, err := Fingerprint(after)
+	if err != nil || x == y {
+		return result, err
+	}
+	if err := Verify(ctx, client, rt.Name, id, before); err != nil {
+		return nil, err
+	}
+	result.Message, err = rt.Put(ctx, client, id, after)
+	if err != nil {
+		return nil, fmt.Errorf("put %s %s failed: %s", rt.Name, id, err)
+	}
+	result.Changed = true
+	return result, nil
+}
+
+// Decode converts a json document to the model of the resource type, failing
+// on the fields the model does not have.
+func Decode(rt *resource.Type, doc interface{}) (interface{}, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	obj := rt.NewModel()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(obj); err != nil {
+		return nil, fmt.Errorf("%s not valid: %s", rt.Name, err)
+	}
+	return obj, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fogatlas/fogatlasctl/pkg/cascade"
+	"github.com/fogatlas/fogatlasctl/pkg/config"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli"
+)
+
+// protecting returns the protection matching a resource of the endpoint of
+// the command, nil if the resource is not protected or if --force-protected is
+// given.
+func protecting(c *cli.Context) (func(res string, id string) *config.Protection, error) {
+	if c.Bool("force-protected") {
+		return func(string, string) *config.Protection { return nil }, nil
+	}
+	conf, err := config.Load(config.Path())
+	if err != nil {
+		return nil, fmt.Errorf("Error: %s", err)
+	}
+	return func(res string, id string) *config.Protection {
+		return conf.Protecting(c.String("endpoint"), res, id)
+	}, nil
+}
+
+// checkProtected fails when one of the resources to be deleted, given as
+// trees (dependents included), is protected by the configuration, unless
+// --force-protected is given.
+func checkProtected(c *cli.Context, trees []*cascade.Tree) error {
+	protection, err := protecting(c)
+	if err != nil {
+		return err
+	}
+	var protected []string
+	var walk func(t *cascade.Tree)
+	walk = func(t *cascade.Tree) {
+		if p := protection(t.Resource, t.ID); p != nil {
+			protected = append(protected, fmt.Sprintf("%s/%s (%s)", t.Resource, t.ID, p))
+		}
+		for _, child := range t.Children {
+			walk(child)
+		}
+	}
+	for _, t := range trees {
+		walk(t)
+	}
+	if len(protected) > 0 {
+		return fmt.Errorf("Error: protected resources cannot be deleted without --force-protected: %s", strings.Join(protected, ", "))
+	}
+	return nil
+}
+
+// protectedTrees returns the trees without dependents of the given resources.
+func protectedTrees(res string, ids []string) []*cascade.Tree {
+	var trees []*cascade.Tree
+	for _, id := range ids {
+		trees = append(trees, &cascade.Tree{Resource: res, ID: id})
+	}
+	return trees
+}
+
+// protectionArgs returns the protection described by the arguments and
+// --context. The resource type is normalized unless it is a pattern.
+func protectionArgs(c *cli.Context) (config.Protection, error) {
+	p := config.Protection{
+		Context:  c.String("context"),
+		Resource: c.Args().Get(0),
+		ID:       c.Args().Get(1),
+	}
+	if c.NArg() > 2 {
+		return p, fmt.Errorf("Error: too many arguments, give a resource type and an identifier pattern")
+	}
+	if p.Resource == "*" {
+		p.Resource = ""
+	}
+	if p.ID == "*" {
+		p.ID = ""
+	}
+	if p.Resource != "" && !strings.ContainsAny(p.Resource, "*?[") {
+		rt, err := resource.Lookup(p.Resource)
+		if err != nil {
+			return p, fmt.Errorf("Error: %s", err)
+		}
+		p.Resource = rt.Name
+	}
+	if err := p.Check(); err != nil {
+		return p, fmt.Errorf("Error: %s", err)
+	}
+	return p, nil
+}
+
+func handleProtect(c *cli.Context) error {
+	path := config.Path()
+	conf, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("Error: %s", err)
+	}
+	if !c.Args().Present() {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Context", "Resource", "ID"})
+		orAny := func(s string) string {
+			if s == "" {
+				return "*"
+			}
+			return s
+		}
+		for _, p := range conf.Protected {
+			table.Append([]string{orAny(p.Context), orAny(p.Resource), orAny(p.ID)})
+		}
+		table.Render()
+		return nil
+	}
+	p, err := protectionArgs(c)
+	if err != nil {
+		return err
+	}
+	if !conf.Protect(p) {
+		fmt.Printf("%s is already protected\n", p)
+		return nil
+	}
+	if err := conf.Save(path); err != nil {
+		return fmt.Errorf("Error: %s", err)
+	}
+	fmt.Printf("%s protected\n", p)
+	return nil
+}
+
+func handleUnprotect(c *cli.Context) error {
+	if !c.Args().Present() {
+		return fmt.Errorf("Error: a resource type is required")
+	}
+	path := config.Path()
+	conf, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("Error: %s", err)
+	}
+	p, err := protectionArgs(c)
+	if err != nil {
+		return err
+	}
+	if !conf.Unprotect(p) {
+		return fmt.Errorf("Error: %s is not protected, list the protections with fogatlasctl protect", p)
+	}
+	if err := conf.Save(path); err != nil {
+		return fmt.Errorf("Error: %s", err)
+	}
+	fmt.Printf("%s unprotected\n", p)
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fogatlas/fogatlasctl/pkg/manifest"
+	"github.com/urfave/cli"
+)
+
+// templateFlags are the options shared by the commands reading templated manifests.
+var templateFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "set",
+		Usage: "value for the manifest templates (.tmpl files) in the key=value form (key can be nested, e.g. region.id=CLOUD). Can be repeated",
+	},
+	cli.StringSliceFlag{
+		Name:  "values",
+		Usage: "yaml file with values for the manifest templates. Can be repeated",
+	},
+	cli.StringFlag{
+		Name:  "overlay",
+		Value: "",
+		Usage: "directory containing an " + manifest.OverlayFile + " that combines base manifests, values and patches",
+	},
+}
+
+// loadConfFromContext loads the resources given with --file and --overlay,
+// rendering the manifest templates with the values given with --values and --set.
+func loadConfFromContext(c *cli.Context) (*manifest.Conf, error) {
+	if len(c.StringSlice("file")) == 0 && c.String("overlay") == "" {
+		return nil, fmt.Errorf("Error: option --file or --overlay is required")
+	}
+	values := make(map[string]interface{})
+	for _, kv := range c.StringSlice("set") {
+		if err := manifest.SetValue(values, kv); err != nil {
+			return nil, fmt.Errorf("Error: %s", err)
+		}
+	}
+	conf, err := manifest.Load(manifest.Options{
+		Files:      c.StringSlice("file"),
+		Overlay:    c.String("overlay"),
+		ValueFiles: c.StringSlice("values"),
+		Values:     values,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error: %s", err)
+	}
+	return conf, nil
+}
+
+// printConf prints the resources of conf either in the grouped format or as
+// kind-tagged manifests.
+func printConf(conf *manifest.Conf, format string) error {
+	out, err := conf.Encode(format)
+	if err != nil {
+		return fmt.Errorf("Error: unable to convert resources: %s", err)
+	}
+	fmt.Print(string(out))
+	return nil
+}
+
+// handleConvert prints the resources loaded in the format given with --to. It
+// serves both convert and render, which differ only in the default format.
+func handleConvert(c *cli.Context) error {
+	conf, err := loadConfFromContext(c)
+	if err != nil {
+		return err
+	}
+	return printConf(conf, c.String("to"))
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fogatlas/client-go/client/operations"
+	"github.com/fogatlas/fogatlasctl/pkg/config"
+	"github.com/fogatlas/fogatlasctl/pkg/printer"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/jroimartin/gocui"
+	"github.com/urfave/cli"
+)
+
+// uiResources are the resource types that can be browsed, selected with the
+// number keys in this order.
+var uiResources = []string{"regions", "nodes", "microservices", "deployments", "applications", "relationships", "externalendpoints", "dynamicnodes"}
+
+// uiChildren maps a resource type to the one listed when drilling down from
+// it, and to the filter that selects the children of a resource.
+var uiChildren = map[string]struct{ resource, filter string }{
+	"regions": {"nodes", "region_id"},
+	"nodes":   {"microservices", "node_id"},
+}
+
+// uiColumns are the fields listed for each resource type, after the identifier.
+var uiColumns = map[string][]string{
+	"regions":           {"location", "tier"},
+	"nodes":             {"region_id", "status", "cpu_available", "memory_available"},
+	"microservices":     {"name", "node_id", "region_id", "status"},
+	"deployments":       {"status", "externalendpoint_id"},
+	"applications":      {"name", "status"},
+	"relationships":     {"endpoint_a", "endpoint_b", "latency", "status"},
+	"externalendpoints": {"region_id", "type", "ip_address"},
+	"dynamicnodes":      {"region_id", "node_id", "ip_address"},
+}
+
+// uiHelp is the list of hotkeys shown in the status bar.
+const uiHelp = "1-8 type  enter drill down  esc back  / filter  r refresh  s deployment status  d delete  q quit"
+
+// uiLevel is one step of the drill-down: a resource type with its filters.
+type uiLevel struct {
+	resource string
+	filters  map[string]string
+	label    string
+	selected int
+}
+
+// uiRow is a resource shown in the list.
+type uiRow struct {
+	id   string
+	text string
+	obj  interface{}
+}
+
+// tui is the state of the full-screen interface started by the ui command.
+type tui struct {
+	client   *operations.Client
+	endpoint string
+	levels   []uiLevel
+	rows     []uiRow
+	filter   string
+	message  string
+	// prompt is the input currently open ("filter", "status" or "delete"),
+	// empty if none
+	prompt string
+}
+
+func (u *tui) level() *uiLevel {
+	return &u.levels[len(u.levels)-1]
+}
+
+// layout creates the views: the list of resources on the left, the details of
+// the selected one on the right, the status bar and, when open, the prompt.
+func (u *tui) layout(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	if v, err := g.SetView("list", 0, 0, maxX/2-1, maxY-3); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Highlight = true
+		v.SelBgColor = gocui.ColorGreen
+		v.SelFgColor = gocui.ColorBlack
+		if _, err := g.SetCurrentView("list"); err != nil {
+			return err
+		}
+		if err := u.refresh(g); err != nil {
+			return err
+		}
+	}
+	if v, err := g.SetView("detail", maxX/2, 0, maxX-1, maxY-3); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "details"
+		v.Wrap = true
+	}
+	if v, err := g.SetView("status", 0, maxY-3, maxX-1, maxY-1); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Frame = false
+	}
+
+	if u.prompt == "" {
+		if _, err := g.View("input"); err == nil {
+			if err := g.DeleteView("input"); err != nil {
+				return err
+			}
+			g.Cursor = false
+			if _, err := g.SetCurrentView("list"); err != nil {
+				return err
+			}
+		}
+		return u.render(g)
+	}
+	if v, err := g.SetView("input", maxX/4, maxY/2-1, maxX*3/4, maxY/2+1); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Editable = true
+		v.Title = u.promptTitle()
+		if u.prompt == "filter" {
+			fmt.Fprint(v, u.filter)
+			v.SetCursor(len(u.filter), 0)
+		}
+		g.Cursor = true
+		if _, err := g.SetCurrentView("input"); err != nil {
+			return err
+		}
+	}
+	return u.render(g)
+}
+
+func (u *tui) promptTitle() string {
+	row := u.selectedRow()
+	switch u.prompt {
+	case "filter":
+		return "filter (enter to apply, esc to cancel)"
+	case "status":
+		return fmt.Sprintf("new status of deployment %s", row.id)
+	case "delete":
+		return fmt.Sprintf("delete %s %s? type yes to confirm", u.level().resource, row.id)
+	}
+	return u.prompt
+}
+
+// refresh reloads the resources of the current level, keeping the selection.
+func (u *tui) refresh(g *gocui.Gui) error {
+	level := u.level()
+	rt, err := resource.Lookup(level.resource)
+	if err != nil {
+		return err
+	}
+	objs, err := resource.List(context.Background(), u.client, rt.Name, level.filters)
+	if err != nil {
+		u.message = fmt.Sprintf("get %s failed: %s", level.resource, err)
+		u.rows = nil
+		return u.render(g)
+	}
+
+	cells := [][]string{}
+	columns := uiColumns[level.resource]
+	for _, obj := range objs {
+		fields := map[string]interface{}{}
+		b, err := json.Marshal(obj)
+		if err == nil {
+			json.Unmarshal(b, &fields)
+		}
+		row := []string{rt.ID(obj)}
+		for _, column := range columns {
+			value := ""
+			if fields[column] != nil {
+				value = fmt.Sprint(fields[column])
+			}
+			row = append(row, value)
+		}
+		cells = append(cells, row)
+	}
+	sort.Slice(cells, func(i, j int) bool { return cells[i][0] < cells[j][0] })
+	widths := make([]int, len(columns)+1)
+	for _, row := range cells {
+		for i, value := range row {
+			if len(value) > widths[i] {
+				widths[i] = len(value)
+			}
+		}
+	}
+
+	byID := map[string]interface{}{}
+	for _, obj := range objs {
+		byID[rt.ID(obj)] = obj
+	}
+	u.rows = nil
+	for _, row := range cells {
+		var text []string
+		for i, value := range row {
+			text = append(text, fmt.Sprintf("%-*s", widths[i], value))
+		}
+		line := strings.Join(text, "  ")
+		if u.filter != "" && !strings.Contains(strings.ToLower(line), strings.ToLower(u.filter)) {
+			continue
+		}
+		u.rows = append(u.rows, uiRow{id: row[0], text: line, obj: byID[row[0]]})
+	}
+	u.message = fmt.Sprintf("%d %s, updated at %s", len(u.rows), level.resource, time.Now().Format("15:04:05"))
+	return u.render(g)
+}
+
+// render writes the rows, the details of the selected row and the status bar.
+func (u *tui) render(g *gocui.Gui) error {
+	list, err := g.View("list")
+	if err != nil {
+		return nil
+	}
+	list.Clear()
+	var crumbs []string
+	for _, level := range u.levels {
+		if level.label != "" {
+			crumbs = append(crumbs, level.label)
+		}
+		crumbs = append(crumbs, level.resource)
+	}
+	list.Title = strings.Join(crumbs, " > ")
+	if u.filter != "" {
+		list.Title += fmt.Sprintf(" (filter: %s)", u.filter)
+	}
+	for _, row := range u.rows {
+		fmt.Fprintln(list, row.text)
+	}
+	u.clampSelection(list)
+
+	if detail, err := g.View("detail"); err == nil {
+		detail.Clear()
+		if row := u.selectedRow(); row.obj != nil {
+			if err := printer.Object(detail, row.obj, "yaml"); err != nil {
+				fmt.Fprint(detail, err)
+			}
+		}
+	}
+	if status, err := g.View("status"); err == nil {
+		status.Clear()
+		fmt.Fprintln(status, u.message)
+		fmt.Fprint(status, uiHelp)
+	}
+	return nil
+}
+
+// clampSelection keeps the selection of the list inside its rows.
+func (u *tui) clampSelection(list *gocui.View) {
+	selected := u.level().selected
+	if selected >= len(u.rows) {
+		selected = len(u.rows) - 1
+	}
+	if selected < 0 {
+		selected = 0
+	}
+	u.selectRow(list, selected)
+}
+
+// selectRow moves the cursor of the list to the given row, scrolling if needed.
+func (u *tui) selectRow(list *gocui.View, selected int) {
+	u.level().selected = selected
+	_, height := list.Size()
+	_, oy := list.Origin()
+	if selected < oy {
+		oy = selected
+	}
+	if height > 0 && selected >= oy+height {
+		oy = selected - height + 1
+	}
+	list.SetOrigin(0, oy)
+	list.SetCursor(0, selected-oy)
+}
+
+// selectedRow returns the selected row, or an empty row if the list is empty.
+func (u *tui) selectedRow() uiRow {
+	selected := u.level().selected
+	if selected < 0 || selected >= len(u.rows) {
+		return uiRow{}
+	}
+	return u.rows[selected]
+}
+
+func (u *tui) move(delta int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		selected := u.level().selected + delta
+		if selected < 0 || selected >= len(u.rows) {
+			return nil
+		}
+		u.selectRow(v, selected)
+		return u.render(g)
+	}
+}
+
+// show lists the resources of the given type from the top level.
+func (u *tui) show(resource string) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		u.levels = []uiLevel{{resource: resource}}
+		u.filter = ""
+		return u.refresh(g)
+	}
+}
+
+// drillDown lists the children of the selected resource.
+func (u *tui) drillDown(g *gocui.Gui, v *gocui.View) error {
+	child, ok := uiChildren[u.level().resource]
+	row := u.selectedRow()
+	if !ok || row.obj == nil {
+		return nil
+	}
+	u.levels = append(u.levels, uiLevel{
+		resource: child.resource,
+		filters:  map[string]string{child.filter: row.id},
+		label:    row.id,
+	})
+	u.filter = ""
+	return u.refresh(g)
+}
+
+// back returns to the previous level of the drill-down.
+func (u *tui) back(g *gocui.Gui, v *gocui.View) error {
+	if len(u.levels) == 1 {
+		return nil
+	}
+	u.levels = u.levels[:len(u.levels)-1]
+	u.filter = ""
+	return u.refresh(g)
+}
+
+func (u *tui) openPrompt(prompt string) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		row := u.selectedRow()
+		switch {
+		case prompt == "status" && (u.level().resource != "deployments" || row.obj == nil):
+			u.message = "select a deployment to change its status"
+			return u.render(g)
+		case prompt == "delete" && row.obj == nil:
+			return nil
+		}
+		u.prompt = prompt
+		return nil
+	}
+}
+
+func (u *tui) closePrompt(g *gocui.Gui, v *gocui.View) error {
+	u.prompt = ""
+	return nil
+}
+
+// submitPrompt applies the value typed in the prompt.
+func (u *tui) submitPrompt(g *gocui.Gui, v *gocui.View) error {
+	value := strings.TrimSpace(v.Buffer())
+	prompt := u.prompt
+	u.prompt = ""
+	row := u.selectedRow()
+	res := u.level().resource
+	switch prompt {
+	case "filter":
+		u.filter = value
+		u.level().selected = 0
+	case "status":
+		if value == "" {
+			return nil
+		}
+		if _, err := resource.PatchDeploymentStatus(context.Background(), u.client, row.id, value); err != nil {
+			u.message = fmt.Sprintf("patch deployment %s failed: %s", row.id, err)
+			return u.render(g)
+		}
+	case "delete":
+		if value != "yes" {
+			u.message = "delete cancelled"
+			return u.render(g)
+		}
+		conf, err := config.Load(config.Path())
+		if err != nil {
+			u.message = err.Error()
+			return u.render(g)
+		}
+		if p := conf.Protecting(u.endpoint, res, row.id); p != nil {
+			u.message = fmt.Sprintf("%s %s is protected (%s), use fogatlasctl delete --force-protected", res, row.id, p)
+			return u.render(g)
+		}
+		if _, err := resource.Delete(context.Background(), u.client, res, row.id); err != nil {
+			u.message = fmt.Sprintf("delete %s %s failed: %s", res, row.id, err)
+			return u.render(g)
+		}
+	}
+	return u.refresh(g)
+}
+
+func (u *tui) keybindings(g *gocui.Gui) error {
+	quit := func(g *gocui.Gui, v *gocui.View) error { return gocui.ErrQuit }
+	bindings := []struct {
+		view    string
+		key     interface{}
+		handler func(*gocui.Gui, *gocui.View) error
+	}{
+		{"", gocui.KeyCtrlC, quit},
+		{"list", 'q', quit},
+		{"list", gocui.KeyArrowDown, u.move(1)},
+		{"list", 'j', u.move(1)},
+		{"list", gocui.KeyArrowUp, u.move(-1)},
+		{"list", 'k', u.move(-1)},
+		{"list", gocui.KeyEnter, u.drillDown},
+		{"list", gocui.KeyArrowRight, u.drillDown},
+		{"list", gocui.KeyEsc, u.back},
+		{"list", gocui.KeyArrowLeft, u.back},
+		{"list", 'r', func(g *gocui.Gui, v *gocui.View) error { return u.refresh(g) }},
+		{"list", '/', u.openPrompt("filter")},
+		{"list", 's', u.openPrompt("status")},
+		{"list", 'd', u.openPrompt("delete")},
+		{"input", gocui.KeyEnter, u.submitPrompt},
+		{"input", gocui.KeyEsc, u.closePrompt},
+	}
+	for i, resource := range uiResources {
+		bindings = append(bindings, struct {
+			view    string
+			key     interface{}
+			handler func(*gocui.Gui, *gocui.View) error
+		}{"list", rune('1' + i), u.show(resource)})
+	}
+	for _, b := range bindings {
+		if err := g.SetKeybinding(b.view, b.key, gocui.ModNone, b.handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func handleUI(c *cli.Context) error {
+	res := "regions"
+	if c.Args().Present() {
+		rt, err := resource.Lookup(c.Args().First())
+		if err != nil {
+			return fmt.Errorf("Error: %s", err)
+		}
+		res = rt.Name
+	}
+	u := &tui{
+		client:   newClient(c),
+		endpoint: c.String("endpoint"),
+		levels:   []uiLevel{{resource: res}},
+	}
+
+	g, err := gocui.NewGui(gocui.OutputNormal)
+	if err != nil {
+		return fmt.Errorf("Error: unable to start the terminal interface: %s", err)
+	}
+	defer g.Close()
+	g.InputEsc = true
+	g.SetManagerFunc(u.layout)
+	if err := u.keybindings(g); err != nil {
+		return err
+	}
+
+	if interval := c.Int("refresh"); interval > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			ticker := time.NewTicker(time.Duration(interval) * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					g.Update(func(g *gocui.Gui) error {
+						// do not change the list while a prompt refers to its selection
+						if u.prompt != "" {
+							return nil
+						}
+						return u.refresh(g)
+					})
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fogatlas/client-go/models"
+	"github.com/fogatlas/fogatlasctl/pkg/deployment"
+	"github.com/fogatlas/fogatlasctl/pkg/manifest"
+	"github.com/fogatlas/fogatlasctl/pkg/resource"
+	"github.com/urfave/cli"
+)
+
+// validateDeployment checks the descriptors of all the microservices of a
+// deployment, printing warnings and errors. It returns the number of errors.
+func validateDeployment(depl *models.Deployment) int {
+	nerrs := 0
+	for _, p := range deployment.Validate(depl) {
+		if p.Warning {
+			fmt.Printf("Warning: deployment %s, microservice %s: %s\n", depl.Name, p.Microservice, p.Message)
+			continue
+		}
+		fmt.Printf("Error: deployment %s, microservice %s: %s\n", depl.Name, p.Microservice, p.Message)
+		nerrs++
+	}
+	return nerrs
+}
+
+func handleValidate(c *cli.Context) error {
+	var objs []interface{}
+	if c.Args().Present() {
+		rt, err := resource.Lookup(c.Args().First())
+		if err != nil {
+			return fmt.Errorf("Error: %s", err)
+		}
+		objs, err = manifest.LoadResources(rt.Name, c.StringSlice("file"))
+		if err != nil {
+			return fmt.Errorf("Error: %s", err)
+		}
+	} else {
+		conf, err := loadConfFromContext(c)
+		if err != nil {
+			return err
+		}
+		for _, item := range conf.Items() {
+			objs = append(objs, item.Obj)
+		}
+	}
+
+	nerrs := 0
+	for _, obj := range objs {
+		if depl, ok := obj.(*models.Deployment); ok {
+			nerrs += validateDeployment(depl)
+		}
+	}
+	if nerrs > 0 {
+		return fmt.Errorf("Error: validation failed with %d errors", nerrs)
+	}
+	fmt.Printf("%d resources are valid\n", len(objs))
+	return nil
+}